@@ -0,0 +1,105 @@
+package nabu
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrorChain returns every nabu frame of the chain held in memory, ordered
+// oldest to newest like ParsedErrorTrace.Frames. The root error is set on the
+// oldest frame. Frames only carry a Date, Function and Line once Log was called.
+// Joined chains (errors.Join) are not followed; their UUIDs are kept in Related.
+func (x *Logger) ErrorChain() []Output {
+	var frames []Output
+	for l := x; l != nil; l = innerLogger(l.CausedBy) {
+		frames = append(frames, l.output())
+	}
+	slices.Reverse(frames)
+	return frames
+}
+
+// Format implements fmt.Formatter.
+// %s and %v print Error(), %q prints it quoted, and %+v prints the root error
+// followed by every frame of the chain with its message, function and line.
+func (x *Logger) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if f.Flag('+') {
+			io.WriteString(f, x.verboseMessage())
+			return
+		}
+		io.WriteString(f, x.Error())
+	case 's':
+		io.WriteString(f, x.Error())
+	case 'q':
+		io.WriteString(f, strconv.Quote(x.Error()))
+	default:
+		fmt.Fprintf(f, "%%!%c(*nabu.Logger=%s)", verb, x.Error())
+	}
+}
+
+// chainMessage renders the messages of the chain from the newest to the root error,
+// e.g. "operation failed: query failed: EOF".
+func (x *Logger) chainMessage() string {
+	var parts []string
+	var e error = x
+	for e != nil {
+		l, ok := e.(*Logger)
+		if !ok {
+			parts = append(parts, e.Error())
+			break
+		}
+		if l.Msg != "" {
+			parts = append(parts, l.Msg)
+		}
+		e = l.CausedBy
+	}
+	return strings.Join(parts, ": ")
+}
+
+// verboseMessage renders the root error and then each frame, oldest first:
+//
+//	EOF
+//	query failed
+//		github.com/acme/db.Query:42
+//	operation failed
+//		main.run:17
+func (x *Logger) verboseMessage() string {
+	var sb strings.Builder
+	frames := x.ErrorChain()
+	if len(frames) > 0 {
+		if frames[0].Error != "" {
+			sb.WriteString(frames[0].Error)
+		} else {
+			sb.WriteString(strings.Join(frames[0].Errors, "\n"))
+		}
+	}
+	for _, f := range frames {
+		if f.Msg != "" {
+			sb.WriteString("\n" + f.Msg)
+		}
+		if f.Function != "" {
+			sb.WriteString("\n\t" + f.Function + ":" + strconv.Itoa(f.Line))
+		}
+	}
+	return strings.TrimPrefix(sb.String(), "\n")
+}
+
+// innerLogger returns the next Logger in the chain wrapped by err.
+// Unwrapping stops at multi-errors (errors.Join), which start a new chain.
+func innerLogger(err error) *Logger {
+	for err != nil {
+		switch x := err.(type) {
+		case *Logger:
+			return x
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
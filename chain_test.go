@@ -0,0 +1,122 @@
+package nabu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrorChain(t *testing.T) {
+	resetTestState()
+	e := a("Chain_")
+
+	var x *Logger
+	if !errors.As(e, &x) {
+		t.Fatal("Expected a *Logger")
+	}
+	frames := x.ErrorChain()
+
+	expectedMsgs := []string{"D", "C", "B", "A"}
+	expectedFunctions := []string{
+		"github.com/rah-0/nabu.d",
+		"github.com/rah-0/nabu.c",
+		"github.com/rah-0/nabu.b",
+		"github.com/rah-0/nabu.a",
+	}
+	if len(frames) != len(expectedMsgs) {
+		t.Fatalf("Expected %d frames, got %d", len(expectedMsgs), len(frames))
+	}
+	for i, f := range frames {
+		if f.Msg != expectedMsgs[i] {
+			t.Errorf("Frame %d: expected Msg %q, got %q", i, expectedMsgs[i], f.Msg)
+		}
+		if f.Function != expectedFunctions[i] {
+			t.Errorf("Frame %d: expected Function %q, got %q", i, expectedFunctions[i], f.Function)
+		}
+		if f.UUID != x.UUID || f.Date == "" || f.Line == 0 {
+			t.Errorf("Frame %d: expected UUID, Date and Line to be recorded, got %+v", i, f)
+		}
+	}
+	if frames[0].Error != "testError" {
+		t.Errorf("Expected root error on the oldest frame, got %q", frames[0].Error)
+	}
+	for _, f := range frames[1:] {
+		if f.Error != "" {
+			t.Errorf("Expected no error on wrapping frames, got %q", f.Error)
+		}
+	}
+}
+
+func TestErrorChainNotLogged(t *testing.T) {
+	root := errors.New("EOF")
+	x := FromError(fmt.Errorf("read: %w", FromError(root).WithMessage("inner"))).WithMessage("outer")
+
+	frames := x.ErrorChain()
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(frames))
+	}
+	if frames[0].Msg != "inner" || frames[0].Error != "EOF" || frames[0].Function != "" {
+		t.Errorf("Unexpected oldest frame: %+v", frames[0])
+	}
+	if frames[1].Msg != "outer" {
+		t.Errorf("Unexpected newest frame: %+v", frames[1])
+	}
+}
+
+func TestErrorMessageChain(t *testing.T) {
+	resetTestState()
+	e := a("Chain_")
+
+	if e.Error() != "testError" {
+		t.Errorf("Expected only the root error by default, got %q", e.Error())
+	}
+
+	SetErrorMessageChain(true)
+	defer SetErrorMessageChain(false)
+
+	if e.Error() != "A: B: C: D: testError" {
+		t.Errorf("Expected the message chain, got %q", e.Error())
+	}
+	if fmt.Sprintf("%v", e) != "A: B: C: D: testError" {
+		t.Errorf("Expected %%v to render Error(), got %q", fmt.Sprintf("%v", e))
+	}
+	if fmt.Sprintf("%q", e) != `"A: B: C: D: testError"` {
+		t.Errorf("Expected %%q to quote Error(), got %s", fmt.Sprintf("%q", e))
+	}
+
+	wrapped := FromError(fmt.Errorf("handler: %w", e)).WithMessage("request failed")
+	if wrapped.Error() != "request failed: handler: A: B: C: D: testError" {
+		t.Errorf("Unexpected message through foreign wrapper: %q", wrapped.Error())
+	}
+}
+
+func TestFormatVerbose(t *testing.T) {
+	resetTestState()
+	e := a("Chain_")
+
+	out := fmt.Sprintf("%+v", e)
+	lines := strings.Split(out, "\n")
+	if lines[0] != "testError" {
+		t.Errorf("Expected root error first, got %q", lines[0])
+	}
+	if lines[1] != "D" || !strings.HasPrefix(lines[2], "\tgithub.com/rah-0/nabu.d:") {
+		t.Errorf("Expected the oldest frame next, got %q", out)
+	}
+	if !strings.HasPrefix(lines[len(lines)-1], "\tgithub.com/rah-0/nabu.a:") {
+		t.Errorf("Expected the newest frame last, got %q", out)
+	}
+}
+
+func TestInnerLogger(t *testing.T) {
+	inner := &Logger{UUID: "inner"}
+	if innerLogger(fmt.Errorf("x: %w", inner)) != inner {
+		t.Error("Expected to unwrap foreign errors up to the Logger")
+	}
+	if innerLogger(errors.Join(inner)) != nil {
+		t.Error("Expected joins not to be followed")
+	}
+	if innerLogger(errors.New("plain")) != nil {
+		t.Error("Expected nil for plain errors")
+	}
+}
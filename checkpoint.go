@@ -0,0 +1,156 @@
+package nabu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpointHeadSize is how much of the start of a file is hashed to recognize it.
+const checkpointHeadSize = 1024
+
+// Checkpoint records how far a Parser has read its input, so that a later run
+// continues from there with ResumeFrom. It is returned in ParsedLogs.Checkpoint
+// and can be persisted with Save and LoadCheckpoint.
+type Checkpoint struct {
+	Path     string   `json:",omitempty"` // File the checkpoint was taken on, empty for other inputs
+	Inode    uint64   `json:",omitempty"` // Identity of the file, zero where not supported
+	Size     int64    `json:",omitempty"` // Size of the file when the checkpoint was taken
+	HeadHash string   `json:",omitempty"` // Hash of the first bytes of the file that were read
+	Offset   int64    // Bytes read, the next run starts there
+	Line     int      // Lines read, so that line numbers go on across runs
+	LastDate string   `json:",omitempty"` // Date of the last entry read
+	LastSeq  uint64   `json:",omitempty"` // Seq of the last entry read
+	Open     []Output `json:",omitempty"` // Frames of the traces held open, see HoldOpenTraces
+}
+
+// ResumeFrom continues reading where a previous run stopped, e.g. in a batch job
+// going over the same file on every run. A file is only read from the checkpoint
+// offset if it is still the same file: same inode, not shorter than the offset and
+// with the same first bytes. Otherwise, e.g. after a rotation, it is read from the
+// start, skipping the entries not newer than the last one read, by Date then Seq.
+// Other inputs are trusted to be the same stream and are read from the offset.
+// The frames of the traces held open by the previous run are grouped again with
+// the new ones by Parse. An unterminated last line that cannot be decoded is left
+// for the next run, since it may still be being written.
+// ResumeFrom is ignored when sources are merged; Entries, Traces and Query start
+// reading from the checkpoint but do not return a new one.
+// A zero Checkpoint reads the whole input.
+func (p *Parser) ResumeFrom(c Checkpoint) *Parser {
+	p.resume = &c
+	return p
+}
+
+// HoldOpenTraces keeps the traces that may still be going on out of
+// ParsedLogs.Traces: traces whose latest frame is less than quiet older than the
+// latest entry of the input are held in the checkpoint instead, and completed by
+// the next run resuming from it.
+func (p *Parser) HoldOpenTraces(quiet time.Duration) *Parser {
+	p.holdQuiet = quiet
+	return p
+}
+
+// Save writes the checkpoint as JSON to path. The file is replaced atomically,
+// so that a job interrupted while saving keeps its previous checkpoint.
+func (c Checkpoint) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadCheckpoint reads a checkpoint written by Save. A missing file yields a zero
+// Checkpoint, so that the first run reads the whole input.
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	var c Checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// track records that the line ending at pos was read, and the entry it held if any.
+func (c *Checkpoint) track(pos scanPosition, entry *Output) {
+	if c == nil {
+		return
+	}
+	c.Offset, c.Line = pos.offset, pos.line
+	if entry != nil {
+		c.LastDate, c.LastSeq = entry.Date, entry.Seq
+	}
+}
+
+// identify records the identity of the file the checkpoint was taken on.
+func (c *Checkpoint) identify(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	c.Path, c.Inode, c.Size = path, fileInode(info), info.Size()
+	c.HeadHash, _ = headHash(path, c.Offset)
+}
+
+// sameFile reports whether the file at path is the one the checkpoint was taken on,
+// possibly with lines appended.
+func (c *Checkpoint) sameFile(path string) bool {
+	if c.Offset == 0 {
+		return true
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < c.Offset {
+		return false
+	}
+	if inode := fileInode(info); c.Inode != 0 && inode != 0 && inode != c.Inode {
+		return false
+	}
+	hash, err := headHash(path, c.Offset)
+	return err == nil && hash == c.HeadHash
+}
+
+// after reports whether an entry was written after the last entry of the checkpoint.
+// Entries whose date cannot be compared are kept.
+func (c *Checkpoint) after(entry Output) bool {
+	last, err1 := time.Parse(TimeLayout, c.LastDate)
+	date, err2 := time.Parse(TimeLayout, entry.Date)
+	if err1 != nil || err2 != nil || date.After(last) {
+		return true
+	}
+	return date.Equal(last) && entry.Seq > c.LastSeq
+}
+
+// headHash hashes the first bytes of the file, up to read bytes.
+func headHash(path string, read int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, min(read, checkpointHeadSize)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package nabu
+
+import "os"
+
+// fileInode returns zero on platforms without inodes, files being recognized by
+// their size and first bytes only.
+func fileInode(os.FileInfo) uint64 {
+	return 0
+}
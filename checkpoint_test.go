@@ -0,0 +1,165 @@
+package nabu
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func entryLine(uuid, date string, seq int, msg string) string {
+	return `{"UUID":"` + uuid + `","Date":"2025-06-25 ` + date + `","Seq":` + strconv.Itoa(seq) + `,"Msg":"` + msg + `","Level":3}` + "\n"
+}
+
+func parseFile(t *testing.T, path string, c Checkpoint) ParsedLogs {
+	t.Helper()
+	p, err := NewParser().FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := p.ResumeFrom(c).Parse()
+	if parsed.Checkpoint == nil {
+		t.Fatal("expected a checkpoint")
+	}
+	return parsed
+}
+
+func traceMsgs(parsed ParsedLogs) []string {
+	var msgs []string
+	for _, e := range parsed.Entries {
+		msgs = append(msgs, e.Msg)
+	}
+	for _, tr := range parsed.Traces {
+		for _, f := range tr.Frames {
+			msgs = append(msgs, f.Msg)
+		}
+	}
+	return msgs
+}
+
+func TestParserResumeFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	first := entryLine("", "01:00:00.000000", 1, "first")
+	appendFile(t, path, first+entryLine("", "01:00:01.000000", 2, "second"))
+
+	parsed := parseFile(t, path, Checkpoint{})
+	c := *parsed.Checkpoint
+	if msgs := traceMsgs(parsed); len(msgs) != 2 {
+		t.Fatalf("expected the whole file, got %v", msgs)
+	}
+	if info, _ := os.Stat(path); c.Offset != info.Size() || c.Line != 2 || c.Path != path || c.HeadHash == "" || c.LastSeq != 2 {
+		t.Errorf("unexpected checkpoint %+v", c)
+	}
+
+	// Entries sharing the date of the last entry read are not lost
+	appendFile(t, path, entryLine("", "01:00:01.000000", 3, "same date")+"{broken\n")
+	parsed = parseFile(t, path, c)
+	if msgs := traceMsgs(parsed); !reflect.DeepEqual(msgs, []string{"same date"}) {
+		t.Errorf("expected only the appended entry, got %v", msgs)
+	}
+	if len(parsed.Errors) != 1 || parsed.Errors[0].Line != 4 {
+		t.Errorf("expected line numbers to go on, got %v", parsed.Errors)
+	}
+	c = *parsed.Checkpoint
+
+	// Nothing new: the checkpoint stays
+	parsed = parseFile(t, path, c)
+	if len(traceMsgs(parsed)) != 0 || parsed.Checkpoint.Offset != c.Offset || parsed.Checkpoint.LastSeq != c.LastSeq {
+		t.Errorf("expected nothing new, got %v %+v", traceMsgs(parsed), parsed.Checkpoint)
+	}
+}
+
+func TestParserResumeFromReplacedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	content := entryLine("", "01:00:00.000000", 1, "first") + entryLine("", "01:00:01.000000", 2, "second")
+	appendFile(t, path, content)
+	c := *parseFile(t, path, Checkpoint{}).Checkpoint
+
+	// A copy with an entry logged within the same microsecond as the last one read
+	next := filepath.Join(dir, "app.log.new")
+	appendFile(t, next, content+entryLine("", "01:00:01.000000", 3, "same date")+entryLine("", "01:00:02.000000", 1, "later"))
+	if err := os.Rename(next, path); err != nil {
+		t.Fatal(err)
+	}
+	parsed := parseFile(t, path, c)
+	if msgs := traceMsgs(parsed); !reflect.DeepEqual(msgs, []string{"same date", "later"}) {
+		t.Errorf("expected the entries after the checkpoint, got %v", msgs)
+	}
+
+	// A truncated file is read from the start
+	if err := os.WriteFile(path, []byte(entryLine("", "01:00:03.000000", 1, "new")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := traceMsgs(parseFile(t, path, *parsed.Checkpoint)); !reflect.DeepEqual(msgs, []string{"new"}) {
+		t.Errorf("expected the truncated file to be read again, got %v", msgs)
+	}
+}
+
+func TestParserResumeFromPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	first := entryLine("", "01:00:00.000000", 1, "first")
+	partial := entryLine("", "01:00:01.000000", 2, "partial")
+	appendFile(t, path, first+partial[:30])
+
+	parsed := parseFile(t, path, Checkpoint{})
+	if len(parsed.Errors) != 0 || parsed.Checkpoint.Offset != int64(len(first)) {
+		t.Errorf("expected the partial line to be left for later, got %v %+v", parsed.Errors, parsed.Checkpoint)
+	}
+
+	appendFile(t, path, partial[30:])
+	if msgs := traceMsgs(parseFile(t, path, *parsed.Checkpoint)); !reflect.DeepEqual(msgs, []string{"partial"}) {
+		t.Errorf("expected the completed line, got %v", msgs)
+	}
+
+	// Without ResumeFrom, the line is reported as usual
+	appendFile(t, path, "{")
+	p, _ := NewParser().FromFile(path)
+	if errs := p.Parse().Errors; len(errs) != 1 || errs[0].Line != 3 {
+		t.Errorf("expected the partial line to be reported, got %v", errs)
+	}
+}
+
+func TestParserHoldOpenTraces(t *testing.T) {
+	lines := []string{
+		entryLine("a", "01:00:00.000000", 1, "a1"),
+		entryLine("b", "01:00:50.000000", 2, "b1"),
+		entryLine("b", "01:01:00.000000", 3, "b2"),
+		entryLine("c", "01:02:00.000000", 4, "c1"),
+	}
+	parsed := NewParser().FromLines(lines[:2]).HoldOpenTraces(30 * time.Second).Parse()
+	if msgs := traceMsgs(parsed); !reflect.DeepEqual(msgs, []string{"a1"}) || len(parsed.Checkpoint.Open) != 1 {
+		t.Fatalf("expected b to be held, got %v %+v", msgs, parsed.Checkpoint)
+	}
+
+	c := roundTrip(t, *parsed.Checkpoint)
+	parsed = NewParser().FromLines(lines).HoldOpenTraces(30 * time.Second).ResumeFrom(c).Parse()
+	if msgs := traceMsgs(parsed); !reflect.DeepEqual(msgs, []string{"b1", "b2"}) {
+		t.Errorf("expected b to be completed, got %v", msgs)
+	}
+	if len(parsed.Checkpoint.Open) != 1 || parsed.Checkpoint.Open[0].UUID != "c" {
+		t.Errorf("expected c to be held, got %+v", parsed.Checkpoint.Open)
+	}
+}
+
+// roundTrip saves and loads a checkpoint.
+func roundTrip(t *testing.T, c Checkpoint) Checkpoint {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if loaded, err := LoadCheckpoint(path); err != nil || !reflect.DeepEqual(loaded, Checkpoint{}) {
+		t.Errorf("expected a zero checkpoint for a missing file, got %+v %v", loaded, err)
+	}
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Offset != c.Offset || len(loaded.Open) != len(c.Open) {
+		t.Errorf("expected %+v, got %+v", c, loaded)
+	}
+	return loaded
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package nabu
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode number of a file, to recognize it after a rename.
+func fileInode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
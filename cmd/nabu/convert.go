@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rah-0/nabu"
+)
+
+// field is a value of a converted entry, nil when not set.
+type field struct {
+	key   string
+	value any // string, int, uint64, bool, json.Number or json.RawMessage
+}
+
+var convertColumns = []string{"date", "level", "uuid", "seq", "function", "line", "msg", "error", "errors", "code", "category", "retryable", "args", "fields", "parent_uuid", "related", "source", "stack"}
+
+// converter encodes entries with the chosen level and time encodings.
+type converter struct {
+	level string // name, upper or number
+	time  string // nabu, rfc3339, unix or unixms
+}
+
+// runConvert rewrites entries as logfmt, CSV or NDJSON, one line per frame of
+// deferred chains, with lowercase keys and the chosen level and time encodings.
+func runConvert(e env, args []string) int {
+	fs := newFlagSet(e, "convert")
+	to := fs.String("to", "logfmt", "Output format: logfmt, csv or ndjson")
+	level := fs.String("level", "name", "Level encoding: name (error), upper (ERROR) or number (3)")
+	timeEncoding := fs.String("time", "nabu", "Date encoding: nabu ("+nabu.TimeLayout+"), rfc3339, unix (seconds) or unixms")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	for _, check := range []struct{ flag, value, allowed string }{
+		{"to", *to, "logfmt csv ndjson"},
+		{"level", *level, "name upper number"},
+		{"time", *timeEncoding, "nabu rfc3339 unix unixms"},
+	} {
+		if !strings.Contains(" "+check.allowed+" ", " "+check.value+" ") {
+			fmt.Fprintf(e.stderr, "nabu: invalid -%s %q, expected one of %s\n", check.flag, check.value, check.allowed)
+			return exitUsage
+		}
+	}
+
+	p, err := newParser(e, fs.Args())
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	c := converter{level: *level, time: *timeEncoding}
+	var cw *csv.Writer
+	if *to == "csv" {
+		cw = csv.NewWriter(e.stdout)
+		cw.Write(convertColumns)
+	}
+
+	rejected := 0
+	for entry, err := range p.Entries() {
+		var pe *nabu.ParseError
+		if errors.As(err, &pe) && pe.Line == 0 {
+			fmt.Fprintln(e.stderr, err)
+			return exitFailure
+		}
+		if err != nil {
+			rejected++
+			continue
+		}
+		for _, f := range nabu.ExpandChain(entry) {
+			fields := c.fields(f)
+			switch *to {
+			case "csv":
+				record := make([]string, len(fields))
+				for i, fd := range fields {
+					record[i] = formatField(fd.value)
+				}
+				err = cw.Write(record)
+			case "ndjson":
+				err = writeNDJSON(e.stdout, fields)
+			default:
+				err = writeLogfmt(e.stdout, fields)
+			}
+			if err != nil {
+				fmt.Fprintln(e.stderr, "nabu:", err)
+				return exitFailure
+			}
+		}
+	}
+	if cw != nil {
+		if cw.Flush(); cw.Error() != nil {
+			fmt.Fprintln(e.stderr, "nabu:", cw.Error())
+			return exitFailure
+		}
+	}
+	reportRejected(e, rejected)
+	return exitOK
+}
+
+// fields returns the values of an entry, in the order of convertColumns.
+func (c converter) fields(o nabu.Output) []field {
+	values := []any{
+		c.date(o.Date), c.levelValue(o.Level), o.UUID, ifSet(o.Seq != 0, o.Seq), o.Function, ifSet(o.Line != 0, o.Line),
+		o.Msg, o.Error, rawJson(o.Errors), o.Code, ifSet(o.Category != nabu.CategoryNone, o.Category.String()),
+		ifSet(o.Retryable, true), rawJson(o.Args), ifSet(len(o.Fields) > 0, rawJson(o.Fields)), o.ParentUUID, rawJson(o.Related), o.Source, o.Stack,
+	}
+	fields := make([]field, len(values))
+	for i, v := range values {
+		if v == "" {
+			v = nil
+		}
+		fields[i] = field{convertColumns[i], v}
+	}
+	return fields
+}
+
+func ifSet(set bool, v any) any {
+	if !set {
+		return nil
+	}
+	return v
+}
+
+func (c converter) levelValue(l nabu.LogLevel) any {
+	switch c.level {
+	case "upper":
+		return strings.ToUpper(l.String())
+	case "number":
+		return int(l)
+	}
+	return l.String()
+}
+
+// date encodes a date, or returns it as is when it is not in nabu.TimeLayout.
+func (c converter) date(date string) any {
+	t, err := time.Parse(nabu.TimeLayout, date)
+	if err != nil || c.time == "nabu" {
+		return date
+	}
+	switch c.time {
+	case "rfc3339":
+		return t.Format(time.RFC3339Nano)
+	case "unixms":
+		return json.Number(strconv.FormatInt(t.UnixMilli(), 10))
+	}
+	return json.Number(fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000))
+}
+
+// rawJson encodes a value as JSON, nil for empty values.
+func rawJson(v any) any {
+	if s, ok := v.([]string); v == nil || ok && len(s) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage(strconv.Quote("!(" + err.Error() + ")"))
+	}
+	return json.RawMessage(b)
+}
+
+func formatField(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.RawMessage:
+		return string(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// writeLogfmt writes the fields that are set as key=value pairs, quoting values when needed.
+func writeLogfmt(w io.Writer, fields []field) error {
+	var sb strings.Builder
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		s := formatField(f.value)
+		if s == "" || strings.ContainsAny(s, " =\"\t\r\n\\") {
+			s = strconv.Quote(s)
+		}
+		sb.WriteString(f.key + "=" + s)
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeNDJSON writes the fields that are set as a JSON object, keeping their order.
+func writeNDJSON(w io.Writer, fields []field) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(strconv.Quote(f.key) + ":")
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunConvert(t *testing.T) {
+	path := writeLogs(t)
+
+	code, stdout, stderr := runCommand(t, "", "convert", path)
+	want := `date="2025-06-25 01:00:00.000000" level=error uuid=a function=db.Query line=10 msg="query failed" error=timeout` + "\n" +
+		`date="2025-06-25 01:00:01.000000" level=error uuid=a function=main.run line=20` + "\n" +
+		`date="2025-06-25 01:00:02.000000" level=warn uuid=b function=http.fetch line=5 error=refused` + "\n"
+	if code != exitOK || stdout != want {
+		t.Errorf("expected logfmt, got %d %q", code, stdout)
+	}
+	if stderr != "nabu: 1 line rejected\n" {
+		t.Errorf("expected rejected lines to be counted, got %q", stderr)
+	}
+
+	code, stdout, _ = runCommand(t, "", "convert", "-to", "csv", "-level", "upper", "-time", "rfc3339", path)
+	lines := strings.Split(stdout, "\n")
+	if code != exitOK || len(lines) != 5 || lines[0] != strings.Join(convertColumns, ",") ||
+		lines[3] != "2025-06-25T01:00:02Z,WARN,b,,http.fetch,5,,refused,,,,,,,,,," {
+		t.Errorf("expected CSV, got %d %q", code, stdout)
+	}
+
+	in := `{"UUID":"c","Date":"2025-06-25 01:00:00.123456","Seq":7,"Error":"gone","Category":2,"Retryable":true,"Args":{"id":1},"Fields":{"service":"api"},"Level":3}` + "\n"
+	code, stdout, _ = runCommand(t, in, "convert", "-to", "ndjson", "-level", "number", "-time", "unixms")
+	if want := `{"date":1750813200123,"level":3,"uuid":"c","seq":7,"error":"gone","category":"not_found","retryable":true,"args":{"id":1},"fields":{"service":"api"}}` + "\n"; code != exitOK || stdout != want {
+		t.Errorf("expected NDJSON, got %d %q", code, stdout)
+	}
+	code, stdout, _ = runCommand(t, in, "convert", "-to", "ndjson", "-time", "unix")
+	if code != exitOK || !strings.HasPrefix(stdout, `{"date":1750813200.123456,"level":"error",`) {
+		t.Errorf("expected dates in seconds, got %d %q", code, stdout)
+	}
+}
+
+func TestRunConvertChains(t *testing.T) {
+	in := `{"UUID":"d","Error":"timeout","Level":3,"Chain":[` +
+		`{"Date":"2025-06-25 01:00:00.000000","Function":"db.Query","Line":10,"Level":3},` +
+		`{"Date":"2025-06-25 01:00:01.000000","Function":"main.run","Line":20,"Level":3}]}` + "\n"
+	code, stdout, _ := runCommand(t, in, "convert")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != exitOK || len(lines) != 2 || !strings.Contains(lines[0], "uuid=d function=db.Query") || !strings.Contains(lines[1], "uuid=d function=main.run") ||
+		!strings.Contains(lines[0], "error=timeout") || strings.Contains(lines[1], "error=") {
+		t.Errorf("expected a line per frame, got %d %q", code, stdout)
+	}
+}
+
+func TestRunConvertErrors(t *testing.T) {
+	for flag, value := range map[string]string{"to": "xml", "level": "emoji", "time": "julian"} {
+		code, _, stderr := runCommand(t, "", "convert", "-"+flag, value)
+		if code != exitUsage || !strings.Contains(stderr, "invalid -"+flag+` "`+value+`"`) {
+			t.Errorf("-%s %s: expected an invalid flag, got %d %q", flag, value, code, stderr)
+		}
+	}
+}
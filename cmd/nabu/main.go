@@ -0,0 +1,196 @@
+// Command nabu reads the logs written by nabu.
+//
+// Usage:
+//
+//	nabu tail [-level LEVEL] [-from-start] [file]
+//	nabu query [-format table|csv|ndjson] QUERY [file|dir ...]
+//	nabu trace [-format text|markdown|html] [-color auto|always|never] UUID [file|dir ...]
+//	nabu report [-format text|markdown|html] [-color auto|always|never] [-uuid UUID] [file|dir ...]
+//	nabu stats [-by level|function|error] [-bucket DURATION] [-top N] [-format text|csv|json] [file|dir ...]
+//	nabu errors [-top N] [-format text|json] [file|dir ...]
+//	nabu convert [-to logfmt|csv|ndjson] [-level name|upper|number] [-time nabu|rfc3339|unix|unixms] [file|dir ...]
+//
+// Logs are read from the given files and directories, compressed or not, or from
+// the standard input when none is given. tail follows a file until interrupted.
+//
+// Like grep, nabu exits with 0 when something was found, 1 when query, trace or
+// errors found nothing, 2 for an invalid command line and 3 when the input could
+// not be read.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/rah-0/nabu"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitNoMatch = 1 // Nothing was found
+	exitUsage   = 2 // Invalid command line
+	exitFailure = 3 // The input could not be read
+)
+
+// env is what a command runs with, so that tests can provide their own.
+type env struct {
+	ctx            context.Context // Done when nabu is interrupted
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+type command struct {
+	summary string
+	run     func(e env, args []string) int
+}
+
+var commands = map[string]command{
+	"tail":    {"Pretty-print live logs", runTail},
+	"query":   {"Select entries with a query", runQuery},
+	"trace":   {"Render the trace with a given UUID", runTrace},
+	"report":  {"Render a readable report of each trace", runReport},
+	"stats":   {"Count entries by level, function or error", runStats},
+	"errors":  {"Group traces by error fingerprint", runErrors},
+	"convert": {"Convert logs to logfmt, CSV or NDJSON", runConvert},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(env{ctx, os.Stdin, os.Stdout, os.Stderr}, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+func run(e env, args []string) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		usage(e.stdout)
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "nabu: unknown command %q\n", args[0])
+		usage(e.stderr)
+		return exitUsage
+	}
+	return cmd.run(e, args[1:])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: nabu <command> [flags] [file|dir ...]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nRun nabu <command> -h for the flags of a command.")
+}
+
+// newFlagSet returns the flags of a command, reporting errors to stderr.
+func newFlagSet(e env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("nabu "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parseFlags parses the flags of a command, returning the exit code to use when it must stop.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// newParser reads the given files and directories, or stdin when there are none.
+// Several paths are merged by date, each file being a source named after its path.
+func newParser(e env, paths []string) (*nabu.Parser, error) {
+	p := nabu.NewParser()
+	if len(paths) == 0 {
+		return p.FromReader(e.stdin).Decompress(), nil
+	}
+	if len(paths) == 1 {
+		info, err := os.Stat(paths[0])
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return p.FromDir(paths[0])
+		}
+		return p.FromFile(paths[0])
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			_, err = p.AddDir(path)
+		} else {
+			_, err = p.AddFile(path, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// reportErrors writes the read errors of parsed logs to stderr, and reports whether
+// the input could not be read at all. Rejected lines are only counted.
+func reportErrors(e env, parsed nabu.ParsedLogs) bool {
+	rejected, failed := 0, false
+	for _, pe := range parsed.Errors {
+		if pe.Line == 0 {
+			fmt.Fprintln(e.stderr, pe.Error())
+			failed = true
+			continue
+		}
+		rejected++
+	}
+	reportRejected(e, rejected)
+	return failed
+}
+
+// reportRejected writes the number of lines that could not be decoded, if any.
+func reportRejected(e env, rejected int) {
+	switch {
+	case rejected == 1:
+		fmt.Fprintln(e.stderr, "nabu: 1 line rejected")
+	case rejected > 1:
+		fmt.Fprintf(e.stderr, "nabu: %d lines rejected\n", rejected)
+	}
+}
+
+// useColor resolves the -color flag, auto enabling colors on terminals unless NO_COLOR is set.
+func useColor(e env, mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		f, ok := e.stdout.(*os.File)
+		if !ok || os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("nabu: invalid -color %q, expected auto, always or never", mode)
+}
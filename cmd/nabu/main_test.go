@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLogs = `{"UUID":"a","Date":"2025-06-25 01:00:00.000000","Error":"timeout","Msg":"query failed","Function":"db.Query","Line":10,"Level":3}
+{"UUID":"a","Date":"2025-06-25 01:00:01.000000","Function":"main.run","Line":20,"Level":3}
+{"UUID":"b","Date":"2025-06-25 01:00:02.000000","Error":"refused","Function":"http.fetch","Line":5,"Level":2}
+{broken
+`
+
+// runCommand runs nabu with args, the given stdin, and returns the exit code, stdout and stderr.
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(env{context.Background(), strings.NewReader(stdin), &stdout, &stderr}, args)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeLogs(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(testLogs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunUsage(t *testing.T) {
+	if code, _, stderr := runCommand(t, ""); code != exitUsage || !strings.Contains(stderr, "report") {
+		t.Errorf("expected the usage, got %d %q", code, stderr)
+	}
+	if code, stdout, _ := runCommand(t, "", "-h"); code != exitOK || !strings.Contains(stdout, "Commands:") {
+		t.Errorf("expected the usage on stdout, got %d %q", code, stdout)
+	}
+	if code, _, stderr := runCommand(t, "", "explode"); code != exitUsage || !strings.Contains(stderr, `unknown command "explode"`) {
+		t.Errorf("expected an unknown command, got %d %q", code, stderr)
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	path := writeLogs(t)
+
+	code, stdout, stderr := runCommand(t, "", "report", path)
+	if code != exitOK || !strings.HasPrefix(stdout, "timeout\n  uuid a\n") || !strings.Contains(stdout, "\nrefused\n") {
+		t.Errorf("expected a text report, got %d %q", code, stdout)
+	}
+	if stderr != "nabu: 1 line rejected\n" {
+		t.Errorf("expected rejected lines to be counted, got %q", stderr)
+	}
+
+	code, stdout, _ = runCommand(t, testLogs, "report", "-format", "markdown", "-uuid", "b")
+	if code != exitOK || stdout != "### refused\n\n- uuid b\n- 2025-06-25 01:00:02.000000, 1 frame\n\n| Offset | Level | Location | Message | Args |\n|---|---|---|---|---|\n| +0s | warn | `http.fetch:5` |  |  |\n" {
+		t.Errorf("expected a Markdown report of b from stdin, got %d %q", code, stdout)
+	}
+
+	if code, stdout, _ = runCommand(t, "", "report", "-format", "html", "-color", "always", path); code != exitOK || !strings.HasPrefix(stdout, "<!DOCTYPE html>") || strings.Contains(stdout, "\x1b[") {
+		t.Errorf("expected an HTML report, got %d %q", code, stdout)
+	}
+	if code, stdout, _ = runCommand(t, "", "report", "-color", "always", path); code != exitOK || !strings.Contains(stdout, "\x1b[") {
+		t.Errorf("expected colors, got %d %q", code, stdout)
+	}
+	if code, stdout, _ = runCommand(t, "", "report", path); strings.Contains(stdout, "\x1b[") {
+		t.Errorf("expected no colors when not on a terminal, got %q", stdout)
+	}
+}
+
+func TestRunReportErrors(t *testing.T) {
+	cases := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"report", "-format", "pdf"}, exitUsage, `unknown report format "pdf"`},
+		{[]string{"report", "-color", "rainbow"}, exitUsage, `invalid -color "rainbow"`},
+		{[]string{"report", "-verbose"}, exitUsage, "flag provided but not defined"},
+		{[]string{"report", filepath.Join(t.TempDir(), "missing.log")}, exitFailure, "no such file"},
+	}
+	for _, c := range cases {
+		code, _, stderr := runCommand(t, "", c.args...)
+		if code != c.code || !strings.Contains(stderr, c.want) {
+			t.Errorf("%v: expected %d and %q, got %d %q", c.args, c.code, c.want, code, stderr)
+		}
+	}
+	if code, _, stderr := runCommand(t, "", "report", "-h"); code != exitOK || !strings.Contains(stderr, "-format") {
+		t.Errorf("expected the flags of report, got %d %q", code, stderr)
+	}
+}
+
+func TestRunSeveralPaths(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(t.TempDir(), "other.log")
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte(testLogs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte(`{"UUID":"c","Date":"2025-06-25 01:00:03.000000","Error":"gone","Level":3}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCommand(t, "", "query", "-format", "csv", "SELECT uuid WHERE error~.", dir, other)
+	if code != exitOK || stdout != "uuid\na\nb\nc\n" || stderr != "nabu: 1 line rejected\n" {
+		t.Errorf("expected the directory and the file to be merged, got %d %q %q", code, stdout, stderr)
+	}
+}
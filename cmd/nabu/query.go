@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rah-0/nabu"
+)
+
+// runQuery prints the entries selected by a query (see nabu.Parser.Query) as an
+// aligned table, as CSV, or as the matching entries in NDJSON.
+func runQuery(e env, args []string) int {
+	fs := newFlagSet(e, "query")
+	format := fs.String("format", "table", "Output format: table, csv or ndjson")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *format != "table" && *format != "csv" && *format != "ndjson" {
+		fmt.Fprintf(e.stderr, "nabu: unknown query format %q\n", *format)
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(e.stderr, "nabu: query expects a query, e.g. 'level>=warn'")
+		return exitUsage
+	}
+
+	p, err := newParser(e, fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	result, err := p.Query(fs.Arg(0))
+	var pe *nabu.ParseError
+	if errors.As(err, &pe) {
+		fmt.Fprintln(e.stderr, err)
+		return exitFailure
+	}
+	if err != nil {
+		fmt.Fprintln(e.stderr, err)
+		return exitUsage
+	}
+	reportRejected(e, len(result.Errors))
+
+	switch *format {
+	case "csv":
+		w := csv.NewWriter(e.stdout)
+		w.Write(result.Columns)
+		w.WriteAll(result.Rows)
+		err = w.Error()
+	case "ndjson":
+		enc := json.NewEncoder(e.stdout)
+		for _, entry := range result.Entries {
+			if err = enc.Encode(entry); err != nil {
+				break
+			}
+		}
+	default:
+		err = writeTable(e, result.Columns, result.Rows)
+	}
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	if len(result.Rows) == 0 {
+		return exitNoMatch
+	}
+	return exitOK
+}
+
+// tableCell keeps values on one line and in their column.
+var tableCell = strings.NewReplacer("\t", " ", "\n", " ", "\r", "")
+
+// writeTable writes rows aligned in columns, under an uppercase header if set.
+func writeTable(e env, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	}
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = tableCell.Replace(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
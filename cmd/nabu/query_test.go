@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunQuery(t *testing.T) {
+	path := writeLogs(t)
+
+	code, stdout, _ := runCommand(t, "", "query", "SELECT uuid, fn WHERE level>=error", path)
+	if code != exitOK || stdout != "UUID  FN\na     db.Query\na     main.run\n" {
+		t.Errorf("expected a table, got %d %q", code, stdout)
+	}
+	code, stdout, _ = runCommand(t, testLogs, "query", "-format", "csv", "SELECT uuid, error WHERE error~ref")
+	if code != exitOK || stdout != "uuid,error\nb,refused\n" {
+		t.Errorf("expected CSV from stdin, got %d %q", code, stdout)
+	}
+	code, stdout, _ = runCommand(t, "", "query", "-format", "ndjson", "uuid=b", path)
+	if code != exitOK || !strings.HasPrefix(stdout, `{"UUID":"b",`) || strings.Count(stdout, "\n") != 1 {
+		t.Errorf("expected the entry of b, got %d %q", code, stdout)
+	}
+
+	if code, _, stderr := runCommand(t, "", "query", "uuid=a", path); stderr != "nabu: 1 line rejected\n" {
+		t.Errorf("expected rejected lines to be counted, got %d %q", code, stderr)
+	}
+	if code, stdout, _ = runCommand(t, "", "query", "uuid=z", path); code != exitNoMatch {
+		t.Errorf("expected no match, got %d %q", code, stdout)
+	}
+	if code, _, stderr := runCommand(t, "", "query", "level>>warn", path); code != exitUsage || stderr == "" {
+		t.Errorf("expected an invalid query, got %d %q", code, stderr)
+	}
+	if code, _, stderr := runCommand(t, "", "query"); code != exitUsage || !strings.Contains(stderr, "expects a query") {
+		t.Errorf("expected a missing query, got %d %q", code, stderr)
+	}
+	if code, _, stderr := runCommand(t, "", "query", "-format", "xml", "uuid=a"); code != exitUsage || !strings.Contains(stderr, `"xml"`) {
+		t.Errorf("expected an unknown format, got %d %q", code, stderr)
+	}
+}
+
+func TestRunQueryReadError(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(testLogs))
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "app.log.gz")
+	if err := os.WriteFile(path, buf.Bytes()[:buf.Len()/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, stderr := runCommand(t, "", "query", "level>=warn", path)
+	if code != exitFailure || !strings.Contains(stderr, "unexpected EOF") {
+		t.Errorf("expected a truncated file to fail, got %d %q", code, stderr)
+	}
+}
+
+func TestRunTrace(t *testing.T) {
+	path := writeLogs(t)
+
+	code, stdout, _ := runCommand(t, "", "trace", "a", path)
+	if code != exitOK || !strings.HasPrefix(stdout, "timeout\n  uuid a\n") || strings.Contains(stdout, "refused") {
+		t.Errorf("expected the trace of a, got %d %q", code, stdout)
+	}
+	if code, _, stderr := runCommand(t, "", "trace", "z", path); code != exitNoMatch || !strings.Contains(stderr, `no trace with UUID "z"`) {
+		t.Errorf("expected no trace, got %d %q", code, stderr)
+	}
+	if code, _, stderr := runCommand(t, "", "trace"); code != exitUsage || !strings.Contains(stderr, "expects a UUID") {
+		t.Errorf("expected a missing UUID, got %d %q", code, stderr)
+	}
+}
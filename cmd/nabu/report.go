@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/rah-0/nabu"
+)
+
+// reportFlags adds the flags of the commands rendering traces, and returns a
+// function resolving them once parsed.
+func reportFlags(e env, fs *flag.FlagSet) func() (nabu.ReportOptions, bool) {
+	format := fs.String("format", "text", "Output format: text, markdown or html")
+	color := fs.String("color", "auto", "Colors of text reports: auto, always or never")
+	return func() (nabu.ReportOptions, bool) {
+		var opts nabu.ReportOptions
+		var err error
+		if opts.Format, err = nabu.ParseReportFormat(*format); err == nil {
+			opts.Color, err = useColor(e, *color)
+		}
+		if err != nil {
+			fmt.Fprintln(e.stderr, err)
+			return opts, false
+		}
+		return opts, true
+	}
+}
+
+func runReport(e env, args []string) int {
+	fs := newFlagSet(e, "report")
+	options := reportFlags(e, fs)
+	uuid := fs.String("uuid", "", "Only report the trace with this UUID")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	opts, ok := options()
+	if !ok {
+		return exitUsage
+	}
+
+	p, err := newParser(e, fs.Args())
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	if *uuid != "" {
+		p.UUID(*uuid)
+	}
+	parsed := p.Parse()
+	if reportErrors(e, parsed) {
+		return exitFailure
+	}
+	if err := parsed.WriteReport(e.stdout, opts); err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runTrace(e env, args []string) int {
+	fs := newFlagSet(e, "trace")
+	options := reportFlags(e, fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	opts, ok := options()
+	if !ok {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(e.stderr, "nabu: trace expects a UUID")
+		return exitUsage
+	}
+	uuid := fs.Arg(0)
+
+	p, err := newParser(e, fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	parsed := p.UUID(uuid).Parse()
+	if reportErrors(e, parsed) {
+		return exitFailure
+	}
+	trace, ok := parsed.Trace(uuid)
+	if !ok {
+		fmt.Fprintf(e.stderr, "nabu: no trace with UUID %q\n", uuid)
+		return exitNoMatch
+	}
+	if err := trace.WriteReport(e.stdout, opts); err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	return exitOK
+}
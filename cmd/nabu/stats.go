@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rah-0/nabu"
+)
+
+// runStats prints nabu.Stats, or with -by the counts of nabu.ParsedLogs.Aggregate.
+func runStats(e env, args []string) int {
+	fs := newFlagSet(e, "stats")
+	by := fs.String("by", "", "Count entries by level, function or error instead of printing every statistic")
+	bucket := fs.Duration("bucket", 0, "With -by, count in buckets of this duration, e.g. 1m")
+	top := fs.Int("top", 10, "Number of most frequent errors to print, all when 0")
+	format := fs.String("format", "text", "Output format: text, csv or json")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	groupBy, ok := map[string]nabu.GroupBy{"level": nabu.GroupByLevel, "function": nabu.GroupByFunction, "error": nabu.GroupByError}[*by]
+	if *by != "" && !ok {
+		fmt.Fprintf(e.stderr, "nabu: invalid -by %q, expected level, function or error\n", *by)
+		return exitUsage
+	}
+	if *format != "text" && *format != "csv" && *format != "json" {
+		fmt.Fprintf(e.stderr, "nabu: unknown stats format %q\n", *format)
+		return exitUsage
+	}
+	if *top < 0 {
+		fmt.Fprintf(e.stderr, "nabu: invalid -top %d, expected 0 or more\n", *top)
+		return exitUsage
+	}
+
+	p, err := newParser(e, fs.Args())
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	parsed := p.Parse()
+	if reportErrors(e, parsed) {
+		return exitFailure
+	}
+
+	// Both nabu.Counts and nabu.Stats write CSV and JSON
+	var out interface {
+		WriteCSV(io.Writer) error
+		WriteJSON(io.Writer) error
+	} = parsed.Stats(*top)
+	if *by != "" {
+		out = parsed.Aggregate(groupBy, *bucket)
+	}
+	switch *format {
+	case "csv":
+		err = out.WriteCSV(e.stdout)
+	case "json":
+		err = out.WriteJSON(e.stdout)
+	default:
+		var buf bytes.Buffer
+		if err = out.WriteCSV(&buf); err == nil {
+			err = writeCSVTable(e, &buf, *by != "" && *bucket == 0)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// writeCSVTable writes CSV as an aligned table, without its first column if dropFirst is set.
+func writeCSVTable(e env, r io.Reader, dropFirst bool) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil || len(records) == 0 {
+		return err
+	}
+	if dropFirst {
+		for i := range records {
+			records[i] = records[i][1:]
+		}
+	}
+	return writeTable(e, records[0], records[1:])
+}
+
+// runErrors prints the error groups of nabu.ParsedLogs.Groups, most frequent first.
+func runErrors(e env, args []string) int {
+	fs := newFlagSet(e, "errors")
+	top := fs.Int("top", 0, "Number of groups to print, all when 0")
+	format := fs.String("format", "text", "Output format: text or json")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(e.stderr, "nabu: unknown errors format %q\n", *format)
+		return exitUsage
+	}
+	if *top < 0 {
+		fmt.Fprintf(e.stderr, "nabu: invalid -top %d, expected 0 or more\n", *top)
+		return exitUsage
+	}
+
+	p, err := newParser(e, fs.Args())
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	parsed := p.Parse()
+	if reportErrors(e, parsed) {
+		return exitFailure
+	}
+	groups := parsed.Groups()
+	if *top > 0 && len(groups) > *top {
+		groups = groups[:*top]
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(groups)
+	} else {
+		rows := make([][]string, len(groups))
+		for i, g := range groups {
+			rows[i] = []string{strconv.Itoa(g.Count), g.Fingerprint, formatTime(g.FirstSeen), formatTime(g.LastSeen), g.Error, strings.Join(g.Functions, " > ")}
+		}
+		err = writeTable(e, []string{"count", "fingerprint", "first seen", "last seen", "error", "functions"}, rows)
+	}
+	if err != nil {
+		fmt.Fprintln(e.stderr, "nabu:", err)
+		return exitFailure
+	}
+	if len(groups) == 0 {
+		return exitNoMatch
+	}
+	return exitOK
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(nabu.TimeLayout)
+}
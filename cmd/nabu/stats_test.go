@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunStats(t *testing.T) {
+	path := writeLogs(t)
+
+	code, stdout, _ := runCommand(t, "", "stats", path)
+	if code != exitOK || !strings.HasPrefix(stdout, "METRIC") || !strings.Contains(stdout, "\nentries                     3\n") {
+		t.Errorf("expected every statistic, got %d %q", code, stdout)
+	}
+	code, stdout, _ = runCommand(t, "", "stats", "-by", "level", path)
+	if code != exitOK || stdout != "KEY    COUNT\nerror  2\nwarn   1\n" {
+		t.Errorf("expected counts by level, got %d %q", code, stdout)
+	}
+	code, stdout, _ = runCommand(t, "", "stats", "-by", "function", "-format", "json", path)
+	var counts any
+	if code != exitOK || json.Unmarshal([]byte(stdout), &counts) != nil {
+		t.Errorf("expected JSON counts, got %d %q", code, stdout)
+	}
+
+	for _, cmd := range []string{"stats", "errors"} {
+		if code, _, stderr := runCommand(t, "", cmd, "-top", "-1", path); code != exitUsage || !strings.Contains(stderr, "invalid -top -1") {
+			t.Errorf("%s: expected an invalid -top, got %d %q", cmd, code, stderr)
+		}
+	}
+	if code, _, stderr := runCommand(t, "", "stats", "-by", "color"); code != exitUsage || !strings.Contains(stderr, `invalid -by "color"`) {
+		t.Errorf("expected an invalid -by, got %d %q", code, stderr)
+	}
+	if code, _, stderr := runCommand(t, "", "stats", "-format", "xml"); code != exitUsage || !strings.Contains(stderr, `"xml"`) {
+		t.Errorf("expected an unknown format, got %d %q", code, stderr)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	path := writeLogs(t)
+
+	code, stdout, _ := runCommand(t, "", "errors", path)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != exitOK || len(lines) != 3 || !strings.HasPrefix(lines[0], "COUNT") || !strings.Contains(lines[1], "db.Query > main.run") {
+		t.Errorf("expected a table of groups, got %d %q", code, stdout)
+	}
+	code, stdout, _ = runCommand(t, "", "errors", "-top", "1", "-format", "json", path)
+	var groups []map[string]any
+	if code != exitOK || json.Unmarshal([]byte(stdout), &groups) != nil || len(groups) != 1 {
+		t.Errorf("expected one group in JSON, got %d %q", code, stdout)
+	}
+
+	if code, stdout, _ = runCommand(t, `{"UUID":"a","Msg":"fine","Level":1}`+"\n", "errors"); code != exitNoMatch {
+		t.Errorf("expected no groups, got %d %q", code, stdout)
+	}
+}
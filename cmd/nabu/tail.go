@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/rah-0/nabu"
+)
+
+// runTail prints entries with nabu.FormatConsole as they are written, and lines
+// that are not JSON as they are. A file is followed across rotations until nabu is
+// interrupted; the standard input is read until it is closed.
+func runTail(e env, args []string) int {
+	fs := newFlagSet(e, "tail")
+	level := fs.String("level", "debug", "Minimum level of the entries to print")
+	fromStart := fs.Bool("from-start", false, "Print the whole file before following it")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	minLevel, err := nabu.ParseLevel(*level)
+	if err != nil {
+		fmt.Fprintln(e.stderr, err)
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(e.stderr, "nabu: tail follows a single file")
+		return exitUsage
+	}
+
+	p := nabu.NewParser().MinLevel(minLevel).KeepUnstructured()
+	var entries iter.Seq2[nabu.Output, error]
+	if fs.NArg() == 0 {
+		entries = p.FromReader(e.stdin).Decompress().Entries()
+	} else {
+		opts := nabu.FollowOptions{}
+		if *fromStart {
+			opts.Start = nabu.FollowFromStart
+		}
+		entries = p.Follow(e.ctx, fs.Arg(0), opts)
+	}
+
+	for entry, err := range entries {
+		var pe *nabu.ParseError
+		switch {
+		case err == nil:
+			fmt.Fprintln(e.stdout, nabu.FormatConsole(entry))
+		case errors.As(err, &pe) && errors.Is(err, nabu.ErrUnstructured):
+			fmt.Fprintln(e.stdout, pe.Raw)
+		case errors.As(err, &pe) && pe.Line == 0:
+			fmt.Fprintln(e.stderr, err)
+			return exitFailure
+		default:
+			fmt.Fprintln(e.stderr, err)
+		}
+	}
+	return exitOK
+}
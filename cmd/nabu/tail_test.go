@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that can be read while a command writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunTail(t *testing.T) {
+	code, stdout, stderr := runCommand(t, testLogs+"plain text\n", "tail", "-level", "warn")
+	want := "2025-06-25 01:00:00.000000 ERROR query failed error=\"timeout\" uuid=a at db.Query:10\n" +
+		"2025-06-25 01:00:01.000000 ERROR uuid=a at main.run:20\n" +
+		"2025-06-25 01:00:02.000000 WARN error=\"refused\" uuid=b at http.fetch:5\n" +
+		"plain text\n"
+	if code != exitOK || stdout != want {
+		t.Errorf("expected the entries of stdin, got %d %q", code, stdout)
+	}
+	if !strings.Contains(stderr, "line 4") {
+		t.Errorf("expected the rejected line, got %q", stderr)
+	}
+
+	if code, _, stderr = runCommand(t, "", "tail", "-level", "loud"); code != exitUsage || stderr == "" {
+		t.Errorf("expected an invalid level, got %d %q", code, stderr)
+	}
+	if code, _, stderr = runCommand(t, "", "tail", "a.log", "b.log"); code != exitUsage || !strings.Contains(stderr, "single file") {
+		t.Errorf("expected a single file, got %d %q", code, stderr)
+	}
+}
+
+func TestRunTailFollow(t *testing.T) {
+	path := writeLogs(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout, stderr syncBuffer
+	done := make(chan int)
+	go func() {
+		done <- run(env{ctx, strings.NewReader(""), &stdout, &stderr}, []string{"tail", "-from-start", path})
+	}()
+
+	waitFor := func(s string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !strings.Contains(stdout.String(), s); {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %q, got %q", s, stdout.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("http.fetch:5")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"UUID":"c","Date":"2025-06-25 01:00:03.000000","Msg":"appended","Level":1}` + "\n")
+	f.Close()
+	waitFor("appended")
+
+	cancel()
+	select {
+	case code := <-done:
+		if code != exitOK {
+			t.Errorf("expected tail to stop cleanly, got %d %q", code, stderr.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tail did not stop once interrupted")
+	}
+}
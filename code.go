@@ -0,0 +1,157 @@
+package nabu
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Category classifies an error so that callers can react to it without string matching.
+type Category int
+
+const (
+	// CategoryNone means the error has not been classified
+	CategoryNone Category = iota
+	// CategoryInvalid is used when the request or input is malformed
+	CategoryInvalid
+	// CategoryNotFound is used when the requested entity does not exist
+	CategoryNotFound
+	// CategoryConflict is used when the entity already exists or was modified concurrently
+	CategoryConflict
+	// CategoryUnauthenticated is used when the caller could not be identified
+	CategoryUnauthenticated
+	// CategoryPermissionDenied is used when the caller is not allowed to perform the operation
+	CategoryPermissionDenied
+	// CategoryRateLimited is used when a quota or rate limit has been exceeded
+	CategoryRateLimited
+	// CategoryTimeout is used when the operation did not complete in time
+	CategoryTimeout
+	// CategoryUnavailable is used when a dependency is temporarily unreachable
+	CategoryUnavailable
+	// CategoryInternal is used for unexpected failures
+	CategoryInternal
+)
+
+// categoryInfo holds the textual name and the status codes a Category maps to.
+type categoryInfo struct {
+	name string
+	http int
+	grpc uint32
+}
+
+// categories lists the mappings of every Category.
+// gRPC codes follow google.golang.org/grpc/codes.
+var categories = map[Category]categoryInfo{
+	CategoryNone:             {"none", http.StatusInternalServerError, 2},        // Unknown
+	CategoryInvalid:          {"invalid", http.StatusBadRequest, 3},              // InvalidArgument
+	CategoryNotFound:         {"not_found", http.StatusNotFound, 5},              // NotFound
+	CategoryConflict:         {"conflict", http.StatusConflict, 6},               // AlreadyExists
+	CategoryUnauthenticated:  {"unauthenticated", http.StatusUnauthorized, 16},   // Unauthenticated
+	CategoryPermissionDenied: {"permission_denied", http.StatusForbidden, 7},     // PermissionDenied
+	CategoryRateLimited:      {"rate_limited", http.StatusTooManyRequests, 8},    // ResourceExhausted
+	CategoryTimeout:          {"timeout", http.StatusGatewayTimeout, 4},          // DeadlineExceeded
+	CategoryUnavailable:      {"unavailable", http.StatusServiceUnavailable, 14}, // Unavailable
+	CategoryInternal:         {"internal", http.StatusInternalServerError, 13},   // Internal
+}
+
+// String returns the name of the category (e.g. "not_found").
+func (c Category) String() string {
+	if info, ok := categories[c]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("Category(%d)", int(c))
+}
+
+// HTTPStatus returns the HTTP status code matching the category.
+// Unclassified and unknown categories map to 500.
+func (c Category) HTTPStatus() int {
+	if info, ok := categories[c]; ok {
+		return info.http
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the gRPC status code matching the category, as defined by
+// google.golang.org/grpc/codes. Unclassified and unknown categories map to Unknown (2).
+func (c Category) GRPCCode() uint32 {
+	if info, ok := categories[c]; ok {
+		return info.grpc
+	}
+	return 2
+}
+
+// WithCode attaches an application-specific error code, e.g. "USER_NOT_FOUND".
+// The code is inherited by the Loggers wrapping this one.
+func (x *Logger) WithCode(code string) *Logger {
+	x.code = code
+	return x
+}
+
+// WithCategory classifies the error. The category is inherited by the Loggers wrapping this one.
+func (x *Logger) WithCategory(c Category) *Logger {
+	x.category = c
+	return x
+}
+
+// Retryable marks the error as safe to retry. This is inherited by the Loggers wrapping this one.
+func (x *Logger) Retryable() *Logger {
+	x.retryable = true
+	return x
+}
+
+// CodeOf returns the code of the nearest nabu Logger in the chain of err that has one.
+func CodeOf(err error) string {
+	for x := innerLogger(err); x != nil; x = innerLogger(x.CausedBy) {
+		if x.code != "" {
+			return x.code
+		}
+	}
+	return ""
+}
+
+// CategoryOf returns the category of the nearest nabu Logger in the chain of err that has one.
+func CategoryOf(err error) Category {
+	for x := innerLogger(err); x != nil; x = innerLogger(x.CausedBy) {
+		if x.category != CategoryNone {
+			return x.category
+		}
+	}
+	return CategoryNone
+}
+
+// IsRetryable reports whether any nabu Logger in the chain of err was marked as Retryable.
+func IsRetryable(err error) bool {
+	for x := innerLogger(err); x != nil; x = innerLogger(x.CausedBy) {
+		if x.retryable {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPStatusOf returns the HTTP status code for err: 200 for nil, otherwise
+// the status of its category (500 when unclassified).
+func HTTPStatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return CategoryOf(err).HTTPStatus()
+}
+
+// GRPCCodeOf returns the gRPC status code for err: OK (0) for nil, otherwise
+// the code of its category (Unknown when unclassified).
+func GRPCCodeOf(err error) uint32 {
+	if err == nil {
+		return 0
+	}
+	return CategoryOf(err).GRPCCode()
+}
+
+// inheritClassification copies the code, category and retryable flag of the wrapped chain.
+func (x *Logger) inheritClassification(inner *Logger) {
+	if inner == nil {
+		return
+	}
+	x.code = CodeOf(inner)
+	x.category = CategoryOf(inner)
+	x.retryable = IsRetryable(inner)
+}
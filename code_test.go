@@ -0,0 +1,103 @@
+package nabu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestClassificationPreservedThroughChain(t *testing.T) {
+	resetTestState()
+
+	inner := FromError(errors.New("no rows")).WithCode("USER_NOT_FOUND").WithCategory(CategoryNotFound).Log()
+	middle := FromError(fmt.Errorf("repo: %w", inner)).WithMessage("lookup failed").Log()
+	outer := FromError(middle).WithMessage("request failed").Log()
+
+	if CodeOf(outer) != "USER_NOT_FOUND" {
+		t.Errorf("Expected code USER_NOT_FOUND, got %q", CodeOf(outer))
+	}
+	if CategoryOf(outer) != CategoryNotFound {
+		t.Errorf("Expected category NotFound, got %v", CategoryOf(outer))
+	}
+	if IsRetryable(outer) {
+		t.Error("Expected error not to be retryable")
+	}
+	if HTTPStatusOf(outer) != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", HTTPStatusOf(outer))
+	}
+	if GRPCCodeOf(outer) != 5 {
+		t.Errorf("Expected gRPC code NotFound (5), got %d", GRPCCodeOf(outer))
+	}
+
+	// Every frame carries the classification in its output
+	parsed := NewParser().FromString(getInternalOutput()).Parse()
+	if len(parsed.Traces) != 1 || len(parsed.Traces[0].Frames) != 3 {
+		t.Fatalf("Expected one trace with 3 frames, got %+v", parsed.Traces)
+	}
+	for i, f := range parsed.Traces[0].Frames {
+		if f.Code != "USER_NOT_FOUND" || f.Category != CategoryNotFound {
+			t.Errorf("Frame %d: expected classification to be serialized, got %+v", i, f)
+		}
+	}
+}
+
+func TestClassificationOverride(t *testing.T) {
+	inner := FromError(errors.New("timeout")).WithCategory(CategoryUnavailable).Retryable()
+	outer := FromError(inner).WithCategory(CategoryInternal).WithCode("UPSTREAM")
+
+	if CategoryOf(outer) != CategoryInternal || CodeOf(outer) != "UPSTREAM" {
+		t.Errorf("Expected the outer classification to win, got %v/%q", CategoryOf(outer), CodeOf(outer))
+	}
+	if !IsRetryable(outer) {
+		t.Error("Expected retryable flag to be inherited")
+	}
+	if CategoryOf(inner) != CategoryUnavailable {
+		t.Errorf("Expected inner category to be unchanged, got %v", CategoryOf(inner))
+	}
+}
+
+func TestClassificationOfForeignErrors(t *testing.T) {
+	plain := errors.New("plain")
+	if CodeOf(plain) != "" || CategoryOf(plain) != CategoryNone || IsRetryable(plain) {
+		t.Error("Expected no classification for foreign errors")
+	}
+	if HTTPStatusOf(plain) != http.StatusInternalServerError || GRPCCodeOf(plain) != 2 {
+		t.Errorf("Expected 500/Unknown for unclassified errors, got %d/%d", HTTPStatusOf(plain), GRPCCodeOf(plain))
+	}
+	if HTTPStatusOf(nil) != http.StatusOK || GRPCCodeOf(nil) != 0 {
+		t.Error("Expected 200/OK for nil errors")
+	}
+
+	// A Logger built without FromError still exposes classification further down its chain
+	manual := New()
+	manual.CausedBy = FromError(plain).WithCode("DEEP")
+	if CodeOf(manual) != "DEEP" {
+		t.Errorf("Expected code from deeper Logger, got %q", CodeOf(manual))
+	}
+}
+
+func TestCategoryMappings(t *testing.T) {
+	cases := []struct {
+		c    Category
+		name string
+		http int
+		grpc uint32
+	}{
+		{CategoryInvalid, "invalid", 400, 3},
+		{CategoryNotFound, "not_found", 404, 5},
+		{CategoryConflict, "conflict", 409, 6},
+		{CategoryUnauthenticated, "unauthenticated", 401, 16},
+		{CategoryPermissionDenied, "permission_denied", 403, 7},
+		{CategoryRateLimited, "rate_limited", 429, 8},
+		{CategoryTimeout, "timeout", 504, 4},
+		{CategoryUnavailable, "unavailable", 503, 14},
+		{CategoryInternal, "internal", 500, 13},
+		{Category(99), "Category(99)", 500, 2},
+	}
+	for _, c := range cases {
+		if c.c.String() != c.name || c.c.HTTPStatus() != c.http || c.c.GRPCCode() != c.grpc {
+			t.Errorf("%s: expected (%s, %d, %d), got (%s, %d, %d)", c.name, c.name, c.http, c.grpc, c.c.String(), c.c.HTTPStatus(), c.c.GRPCCode())
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package nabu
 
-import "sync"
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+)
 
 var (
 	// configMutex protects access to global configuration variables
@@ -17,25 +23,136 @@ var (
 	// internalOutput is a buffer used to capture logs for testing
 	// when OutputInternal is selected
 	internalOutput string
+
+	// errorMessageChain makes Logger.Error include the messages of the chain
+	errorMessageChain bool
+
+	// deferredChains makes intermediate error frames wait for a boundary or Report
+	deferredChains bool
+
+	// panicLevel is the level of the entries written for recovered panics
+	panicLevel = LevelFatal
+
+	// panicRepanic makes Recover panic again after logging
+	panicRepanic bool
+
+	// levelGeneration is incremented on every level change so that
+	// pending TTL reverts can detect they have been superseded
+	levelGeneration uint64
+
+	// timeFormat is the layout of the Date of written entries
+	timeFormat = TimeLayout
+
+	// staticFields are added to every written entry, never modified in place
+	staticFields map[string]any
 )
 
 // SetLogLevel configures the minimum log level that will be processed.
 // Logs with a level lower than this will be ignored.
 // Default is LevelDebug (all logs will be displayed).
+// The change is logged as an audit entry and cancels any pending SetLogLevelFor revert.
 func SetLogLevel(l LogLevel) {
-	configMutex.Lock()
-	defer configMutex.Unlock()
-	logLevel = l
+	s := currentLevelState()
+	s.level = l
+	changeLevelState(s, 0, "api")
+}
+
+// GetLogLevel returns the minimum log level currently being processed.
+func GetLogLevel() LogLevel {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return logLevel
 }
 
 // SetLogOutput configures where logs will be written.
-// Options are OutputStderr (default), OutputStdout, or OutputInternal.
+// Options are OutputStderr (default), OutputStdout, OutputInternal, or OutputNone.
 func SetLogOutput(o LogOutput) {
 	configMutex.Lock()
 	defer configMutex.Unlock()
 	logOutput = o
 }
 
+// SetTimeFormat configures the layout of the Date of written entries, e.g. time.RFC3339Nano.
+// Dates are always in UTC. Parser orders and filters by date only entries written
+// with the default, TimeLayout; other entries are kept, with their Date as is.
+func SetTimeFormat(layout string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	if layout == "" {
+		layout = TimeLayout
+	}
+	timeFormat = layout
+}
+
+// SetFields configures static fields added to every entry, e.g. the service name
+// and environment. The map is copied; nil removes them.
+func SetFields(fields map[string]any) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	staticFields = cloneFields(fields)
+}
+
+func cloneFields(fields map[string]any) map[string]any {
+	if len(fields) == 0 {
+		return nil
+	}
+	return maps.Clone(fields)
+}
+
+// SetErrorMessageChain configures whether Logger.Error includes the messages
+// added along the chain, e.g. "operation failed: query failed: EOF" instead of "EOF".
+// Default is false (only the underlying error is returned).
+func SetErrorMessageChain(enabled bool) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	errorMessageChain = enabled
+}
+
+func errorMessageChainEnabled() bool {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return errorMessageChain
+}
+
+// SetDeferredChains configures whether error chains are written as a single entry.
+// When enabled, Log on an error Logger only records its frame in memory, and the
+// whole chain is written once a Logger marked with Boundary logs it or Report is called.
+// Message logs are not affected. Default is false (every Log writes a line).
+func SetDeferredChains(enabled bool) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	deferredChains = enabled
+}
+
+func deferredChainsEnabled() bool {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return deferredChains
+}
+
+// SetPanicLevel configures the level of the entries written for recovered panics.
+// Default is LevelFatal.
+func SetPanicLevel(l LogLevel) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	panicLevel = l
+}
+
+// SetPanicRepanic configures whether Recover, RecoverContext and Go panic again
+// with the original value once the panic has been logged.
+// Default is false (the panic is swallowed).
+func SetPanicRepanic(enabled bool) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	panicRepanic = enabled
+}
+
+func getPanicConfig() (LogLevel, bool) {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return panicLevel, panicRepanic
+}
+
 // shouldLog determines if a log with the given level should be processed
 // based on the current configured log level.
 func shouldLog(l LogLevel) bool {
@@ -43,3 +160,31 @@ func shouldLog(l LogLevel) bool {
 	defer configMutex.RUnlock()
 	return l >= logLevel
 }
+
+// writeLog sends a log entry to the configured output destination and to every sink,
+// once static fields are added and sensitive values redacted.
+// Sinks are called outside of the lock so that they may log themselves.
+func writeLog(o Output) {
+	configMutex.RLock()
+	o.Fields = staticFields
+	rules := redaction
+	configMutex.RUnlock()
+	o = rules.apply(o)
+	log := toJson(o)
+
+	configMutex.Lock()
+	switch logOutput {
+	case OutputInternal:
+		internalOutput += strings.TrimSpace(log) + "\n"
+	case OutputStdout:
+		fmt.Fprintln(os.Stdout, log)
+	case OutputStderr:
+		fmt.Fprintln(os.Stderr, log)
+	}
+	current := sinks
+	configMutex.Unlock()
+
+	for _, s := range current {
+		s.sink.WriteEntry(o)
+	}
+}
@@ -1,7 +1,10 @@
 package nabu
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestShouldLog(t *testing.T) {
@@ -67,3 +70,38 @@ func TestShouldLog(t *testing.T) {
 		t.Errorf("Expected shouldLog(LevelFatal) to return true at LevelFatal, but got false")
 	}
 }
+
+func TestSetTimeFormatAndFields(t *testing.T) {
+	SetTimeFormat(time.RFC3339)
+	defer SetTimeFormat("")
+	SetFields(map[string]any{"service": "api"})
+	defer SetFields(nil)
+	resetTestState()
+
+	FromMessage("formatted").Log()
+	entry := fromJson(getInternalOutput())
+	if entry == nil || entry.Fields["service"] != "api" {
+		t.Fatalf("Expected the static fields, got %s", getInternalOutput())
+	}
+	if _, err := time.Parse(time.RFC3339, entry.Date); err != nil {
+		t.Errorf("Expected an RFC 3339 date, got %q", entry.Date)
+	}
+
+	t.Run("Parsed", func(t *testing.T) {
+		resetTestState()
+		FromMessage("first").Log()
+		FromError(errors.New("boom")).WithMessage("second").Log()
+		parsed := NewParser().FromString(getInternalOutput()).Parse()
+		if len(parsed.Errors) != 0 || len(parsed.Traces) != 2 || parsed.Traces[1].Error != "boom" {
+			t.Errorf("Expected entries with a custom date format to be kept, got %+v and %v", parsed.Traces, parsed.Errors)
+		}
+	})
+
+	SetFields(nil)
+	SetTimeFormat("")
+	resetTestState()
+	FromMessage("default").Log()
+	if out := getInternalOutput(); strings.Contains(out, "Fields") || !strings.Contains(out, `"Date":"`+time.Now().UTC().Format("2006-01-02 ")) {
+		t.Errorf("Expected the defaults to be restored, got %s", out)
+	}
+}
@@ -0,0 +1,83 @@
+package nabu
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FormatConsole renders an entry as a human-readable line, for terminals and test output:
+//
+//	2025-06-25 01:26:02.408736 ERROR query failed error="EOF" args=["userID",42] uuid=0a1f... at main.query:42
+//
+// Only the fields that are set are included. The frames of a deferred chain
+// (see SetDeferredChains) follow on indented lines, oldest first.
+func FormatConsole(o Output) string {
+	var sb strings.Builder
+	write := func(s string) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(s)
+	}
+
+	if o.Date != "" {
+		write(o.Date)
+	}
+	write(strings.ToUpper(o.Level.String()))
+	if o.Msg != "" {
+		write(o.Msg)
+	}
+	if o.Error != "" {
+		write("error=" + strconv.Quote(o.Error))
+	}
+	if len(o.Errors) > 0 {
+		write("errors=" + consoleJson(o.Errors))
+	}
+	if o.Code != "" {
+		write("code=" + o.Code)
+	}
+	if o.Category != CategoryNone {
+		write("category=" + o.Category.String())
+	}
+	if o.Retryable {
+		write("retryable")
+	}
+	if o.Args != nil {
+		write("args=" + consoleJson(o.Args))
+	}
+	keys := make([]string, 0, len(o.Fields))
+	for k := range o.Fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		write(k + "=" + consoleJson(o.Fields[k]))
+	}
+	if o.UUID != "" {
+		write("uuid=" + o.UUID)
+	}
+	if len(o.Related) > 0 {
+		write("related=" + strings.Join(o.Related, ","))
+	}
+	if o.Function != "" {
+		write("at " + o.Function + ":" + strconv.Itoa(o.Line))
+	}
+	for _, f := range o.Chain {
+		sb.WriteString("\n    " + FormatConsole(f))
+	}
+	if o.Stack != "" {
+		sb.WriteString("\n    " + strings.ReplaceAll(strings.TrimSpace(o.Stack), "\n", "\n    "))
+	}
+	return sb.String()
+}
+
+// consoleJson renders a value compactly as JSON, falling back to an error marker.
+func consoleJson(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "!(" + err.Error() + ")"
+	}
+	return string(b)
+}
@@ -0,0 +1,58 @@
+package nabu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatConsole(t *testing.T) {
+	o := Output{
+		UUID:     "0a1f",
+		Date:     "2025-06-25 01:26:02.408736",
+		Error:    "EOF",
+		Args:     []any{"userID", 42},
+		Msg:      "query failed",
+		Function: "main.query",
+		Line:     42,
+		Level:    LevelError,
+		Code:     "DB_READ",
+		Category: CategoryUnavailable,
+	}
+	expected := `2025-06-25 01:26:02.408736 ERROR query failed error="EOF" code=DB_READ category=unavailable args=["userID",42] uuid=0a1f at main.query:42`
+	if got := FormatConsole(o); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if got := FormatConsole(Output{Msg: "fields", Fields: map[string]any{"service": "api", "replica": 2}}); got != `DEBUG fields replica=2 service="api"` {
+		t.Errorf("Expected static fields sorted by key, got %q", got)
+	}
+	if got := FormatConsole(Output{Msg: "bare"}); got != "DEBUG bare" {
+		t.Errorf("Expected only the set fields, got %q", got)
+	}
+}
+
+func TestFormatConsoleChainAndStack(t *testing.T) {
+	o := Output{
+		UUID:  "0a1f",
+		Error: "EOF",
+		Level: LevelFatal,
+		Chain: []Output{
+			{Msg: "read", Function: "main.read", Line: 3, Level: LevelError},
+			{Msg: "handle", Function: "main.handle", Line: 9, Level: LevelError},
+		},
+		Stack: "goroutine 1 [running]:\nmain.main()\n",
+	}
+	lines := strings.Split(FormatConsole(o), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 lines, got %d: %q", len(lines), lines)
+	}
+	if lines[0] != `FATAL error="EOF" uuid=0a1f` {
+		t.Errorf("Unexpected first line: %q", lines[0])
+	}
+	if lines[1] != "    ERROR read at main.read:3" || lines[2] != "    ERROR handle at main.handle:9" {
+		t.Errorf("Expected indented frames, got %q", lines[1:3])
+	}
+	if lines[3] != "    goroutine 1 [running]:" {
+		t.Errorf("Expected the indented stack, got %q", lines[3])
+	}
+}
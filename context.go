@@ -0,0 +1,21 @@
+package nabu
+
+import "context"
+
+// uuidKey is the context key under which the correlation UUID is stored.
+type uuidKey struct{}
+
+// ContextWithUuid returns a copy of ctx carrying the UUID, so that logs created
+// further down the call stack (e.g. by RecoverContext) can be correlated with it.
+func ContextWithUuid(ctx context.Context, uuid string) context.Context {
+	return context.WithValue(ctx, uuidKey{}, uuid)
+}
+
+// UuidFromContext returns the UUID stored by ContextWithUuid, or an empty string.
+func UuidFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	uuid, _ := ctx.Value(uuidKey{}).(string)
+	return uuid
+}
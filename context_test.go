@@ -0,0 +1,20 @@
+package nabu
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextUuid(t *testing.T) {
+	ctx := ContextWithUuid(context.Background(), "ctx-uuid")
+	if UuidFromContext(ctx) != "ctx-uuid" {
+		t.Errorf("Expected ctx-uuid, got %q", UuidFromContext(ctx))
+	}
+	if UuidFromContext(context.Background()) != "" {
+		t.Error("Expected empty UUID for a context without one")
+	}
+	var nilCtx context.Context
+	if UuidFromContext(nilCtx) != "" {
+		t.Error("Expected empty UUID for a nil context")
+	}
+}
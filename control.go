@@ -0,0 +1,252 @@
+package nabu
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+var (
+	// revertTimer restores the previous level once a temporary level expires
+	revertTimer *time.Timer
+
+	// revertAt is the moment the pending revert will happen (zero if none)
+	revertAt time.Time
+
+	// signalSavedLevel holds the level in effect before the first lowering
+	// triggered by LowerLogLevel, so RestoreLogLevel can return to it
+	signalSavedLevel *LogLevel
+)
+
+// levelStatus is the JSON document served and accepted by LevelHandler.
+type levelStatus struct {
+	Level     string  `json:",omitempty"` // Current (or requested) minimum level
+	Rules     *string `json:",omitempty"` // Per-package overrides, see SetLevelRules
+	TTL       string  `json:",omitempty"` // Requested duration before reverting, e.g. "10m"
+	RevertsAt string  `json:",omitempty"` // When the current levels will be reverted
+}
+
+// levelState is a snapshot of the global level and its overrides.
+type levelState struct {
+	level LogLevel
+	rules []levelRule
+}
+
+// SetLogLevelFor changes the log level for the given duration only.
+// Once the TTL expires the previous level is restored, unless the level was
+// changed again in the meantime. A TTL of zero makes the change permanent.
+// Both the change and the revert are logged as audit entries.
+func SetLogLevelFor(l LogLevel, ttl time.Duration) {
+	s := currentLevelState()
+	s.level = l
+	changeLevelState(s, ttl, "api")
+}
+
+// SetLevelRulesFor applies a spec as SetLevelRules does, for the given duration only.
+// Once the TTL expires the previous level and rules are restored.
+func SetLevelRulesFor(spec string, ttl time.Duration) error {
+	global, rules, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	s := currentLevelState()
+	if global != nil {
+		s.level = *global
+	}
+	s.rules = rules
+	changeLevelState(s, ttl, "api")
+	return nil
+}
+
+// LowerLogLevel decreases the log level by one step (e.g. Warn to Info),
+// stopping at LevelDebug. The level in effect before the first call is
+// remembered so that RestoreLogLevel can return to it.
+func LowerLogLevel() {
+	lowerLogLevel("api")
+}
+
+// RestoreLogLevel returns to the level that was in effect before
+// LowerLogLevel was first called. It does nothing if no level was saved.
+func RestoreLogLevel() {
+	restoreLogLevel("api")
+}
+
+// LevelHandler returns an http.Handler to inspect and change the log level at runtime.
+// GET reports the current level and overrides. PUT changes them using a JSON body such as
+// {"Level":"debug","Rules":"github.com/acme/db/*=debug","TTL":"10m"}; omitted fields are
+// left unchanged, and when TTL is set the previous state is restored once it expires.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(serveLevel)
+}
+
+func serveLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req levelStatus
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Level == "" && req.Rules == nil {
+			http.Error(w, "either Level or Rules must be set", http.StatusBadRequest)
+			return
+		}
+		s := currentLevelState()
+		if req.Rules != nil {
+			global, rules, err := parseLevelSpec(*req.Rules)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if global != nil {
+				s.level = *global
+			}
+			s.rules = rules
+		}
+		if req.Level != "" {
+			l, err := ParseLevel(req.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			s.level = l
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			var err error
+			ttl, err = time.ParseDuration(req.TTL)
+			if err != nil || ttl < 0 {
+				http.Error(w, "invalid TTL: "+req.TTL, http.StatusBadRequest)
+				return
+			}
+		}
+		changeLevelState(s, ttl, "http")
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(currentLevelStatus())
+}
+
+// currentLevelStatus returns the level state as served by LevelHandler.
+func currentLevelStatus() levelStatus {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	rules := formatLevelRules(levelRules)
+	s := levelStatus{Level: logLevel.String(), Rules: &rules}
+	if !revertAt.IsZero() {
+		s.RevertsAt = revertAt.UTC().Format(TimeLayout)
+	}
+	return s
+}
+
+// currentLevelState returns a snapshot of the global level and its overrides.
+func currentLevelState() levelState {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return levelState{level: logLevel, rules: levelRules}
+}
+
+// changeLevelState applies a new state, cancels any pending revert and schedules
+// a new one when ttl is positive. The change, if any, is recorded as an audit entry.
+func changeLevelState(next levelState, ttl time.Duration, source string) {
+	configMutex.Lock()
+	previous := levelState{level: logLevel, rules: levelRules}
+	applyLevelStateLocked(next)
+	generation := levelGeneration
+	stopRevertLocked()
+	if ttl > 0 {
+		revertAt = time.Now().Add(ttl)
+		revertTimer = time.AfterFunc(ttl, func() {
+			revertLevelState(previous, generation)
+		})
+	}
+	configMutex.Unlock()
+
+	auditLevelChange(previous, next, source, ttl)
+}
+
+// revertLevelState restores a state once its TTL has expired,
+// unless another change happened after the temporary state was set.
+func revertLevelState(previous levelState, generation uint64) {
+	configMutex.Lock()
+	if levelGeneration != generation {
+		configMutex.Unlock()
+		return
+	}
+	current := levelState{level: logLevel, rules: levelRules}
+	applyLevelStateLocked(previous)
+	revertTimer = nil
+	revertAt = time.Time{}
+	configMutex.Unlock()
+
+	auditLevelChange(current, previous, "ttl", 0)
+}
+
+// applyLevelStateLocked installs a state. configMutex must be held.
+func applyLevelStateLocked(s levelState) {
+	logLevel = s.level
+	setLevelRulesLocked(s.rules)
+	levelGeneration++
+}
+
+// stopRevertLocked cancels a pending revert. configMutex must be held.
+func stopRevertLocked() {
+	if revertTimer != nil {
+		revertTimer.Stop()
+		revertTimer = nil
+	}
+	revertAt = time.Time{}
+}
+
+func lowerLogLevel(source string) {
+	s := currentLevelState()
+	configMutex.Lock()
+	if signalSavedLevel == nil {
+		saved := s.level
+		signalSavedLevel = &saved
+	}
+	configMutex.Unlock()
+
+	if s.level > LevelDebug {
+		s.level--
+		changeLevelState(s, 0, source)
+	}
+}
+
+func restoreLogLevel(source string) {
+	configMutex.Lock()
+	saved := signalSavedLevel
+	signalSavedLevel = nil
+	configMutex.Unlock()
+
+	if saved != nil {
+		s := currentLevelState()
+		s.level = *saved
+		changeLevelState(s, 0, source)
+	}
+}
+
+// auditLevelChange records a change of level or overrides, if anything changed.
+// Audit entries are written regardless of the configured log level.
+func auditLevelChange(from, to levelState, source string, ttl time.Duration) {
+	fromRules, toRules := formatLevelRules(from.rules), formatLevelRules(to.rules)
+	if from.level == to.level && fromRules == toRules {
+		return
+	}
+	args := []any{"From", from.level.String(), "To", to.level.String(), "Source", source}
+	if fromRules != toRules {
+		args = append(args, "FromRules", fromRules, "ToRules", toRules)
+	}
+	if ttl > 0 {
+		args = append(args, "TTL", ttl.String())
+	}
+	x := FromMessage("log level changed").WithArgs(args...).WithLevelWarn()
+	x.audit = true
+	x.Log()
+}
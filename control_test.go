@@ -0,0 +1,253 @@
+package nabu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelHandlerGet(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelWarn)
+
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/level", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var status levelStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.Level != "warn" {
+		t.Errorf("Expected level 'warn', got %q", status.Level)
+	}
+}
+
+func TestLevelHandlerPut(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelError)
+	resetTestState()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(`{"Level":"debug"}`))
+	LevelHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if GetLogLevel() != LevelDebug {
+		t.Errorf("Expected level to be Debug, got %v", GetLogLevel())
+	}
+
+	entry := fromJson(getInternalOutput())
+	if entry == nil {
+		t.Fatal("Expected an audit entry to be logged")
+	}
+	if entry.Msg != "log level changed" {
+		t.Errorf("Expected audit message, got %q", entry.Msg)
+	}
+	expectedArgs := []any{"From", "error", "To", "debug", "Source", "http"}
+	if !reflect.DeepEqual(entry.Args, expectedArgs) {
+		t.Errorf("Expected audit args %v, got %v", expectedArgs, entry.Args)
+	}
+}
+
+func TestLevelHandlerAuditBypassesLevel(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelDebug)
+	resetTestState()
+
+	// Raising the level to Fatal must still produce the Warn audit entry
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(`{"Level":"fatal"}`))
+	LevelHandler().ServeHTTP(rec, req)
+
+	if !strings.Contains(getInternalOutput(), "log level changed") {
+		t.Error("Expected audit entry to be written regardless of level")
+	}
+}
+
+func TestLevelHandlerInvalid(t *testing.T) {
+	cases := []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{http.MethodPut, `not json`, http.StatusBadRequest},
+		{http.MethodPut, `{"Level":"verbose"}`, http.StatusBadRequest},
+		{http.MethodPut, `{"Level":"info","TTL":"soon"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"Level":"info"}`, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		LevelHandler().ServeHTTP(rec, httptest.NewRequest(c.method, "/level", strings.NewReader(c.body)))
+		if rec.Code != c.code {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.body, c.code, rec.Code)
+		}
+	}
+}
+
+func TestSetLogLevelForReverts(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelError)
+	resetTestState()
+
+	SetLogLevelFor(LevelDebug, 20*time.Millisecond)
+	if GetLogLevel() != LevelDebug {
+		t.Fatalf("Expected level to be Debug, got %v", GetLogLevel())
+	}
+	if currentLevelStatus().RevertsAt == "" {
+		t.Error("Expected RevertsAt to be reported while a TTL is pending")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for GetLogLevel() != LevelError && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if GetLogLevel() != LevelError {
+		t.Fatalf("Expected level to revert to Error, got %v", GetLogLevel())
+	}
+	if !strings.Contains(getInternalOutput(), `"Source","ttl"`) {
+		t.Errorf("Expected revert audit entry, got: %s", getInternalOutput())
+	}
+}
+
+func TestSetLogLevelForSuperseded(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelError)
+
+	SetLogLevelFor(LevelDebug, 20*time.Millisecond)
+	SetLogLevel(LevelInfo)
+
+	time.Sleep(60 * time.Millisecond)
+	if GetLogLevel() != LevelInfo {
+		t.Errorf("Expected superseded revert to be ignored, got %v", GetLogLevel())
+	}
+}
+
+func TestSetLogLevelCancelsRevert(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelError)
+
+	SetLogLevelFor(LevelDebug, 20*time.Millisecond)
+	SetLogLevel(LevelInfo)
+	if currentLevelStatus().RevertsAt != "" {
+		t.Error("Expected SetLogLevel to cancel the pending revert")
+	}
+}
+
+func TestDirectLevelChangesAudited(t *testing.T) {
+	defer SetLevelRules(GetLevelRules())
+	defer SetLogLevel(GetLogLevel())
+
+	changes := []struct {
+		name   string
+		change func()
+		want   string
+	}{
+		{"SetLogLevel", func() { SetLogLevel(LevelWarn) }, `"To","warn"`},
+		{"SetLevelRules", func() { SetLevelRules("info,github.com/acme/db/*=debug") }, `"ToRules","github.com/acme/db/*=debug"`},
+		{"ClearLevelRules", ClearLevelRules, `"FromRules","github.com/acme/db/*=debug","ToRules",""`},
+	}
+	for _, c := range changes {
+		resetTestState()
+		c.change()
+		out := getInternalOutput()
+		if !strings.Contains(out, "log level changed") || !strings.Contains(out, c.want) || !strings.Contains(out, `"Source","api"`) {
+			t.Errorf("%s: expected an audit entry with %s, got: %s", c.name, c.want, out)
+		}
+	}
+}
+
+func TestUnchangedLevelNotAudited(t *testing.T) {
+	defer SetLevelRules(GetLevelRules())
+	defer SetLogLevel(GetLogLevel())
+	SetLevelRules("info,github.com/acme/db/*=debug")
+
+	changes := map[string]func(){
+		"SetLogLevel":   func() { SetLogLevel(LevelInfo) },
+		"SetLevelRules": func() { SetLevelRules("info,github.com/acme/db/*=debug") },
+		"ApplyConfig": func() {
+			ApplyConfig(Config{Level: "info", Output: "internal", Rules: map[string]string{"github.com/acme/db/*": "debug"}})
+		},
+	}
+	for name, change := range changes {
+		resetTestState()
+		change()
+		if out := getInternalOutput(); strings.Contains(out, "log level changed") {
+			t.Errorf("%s: expected no audit entry for an unchanged level, got: %s", name, out)
+		}
+	}
+}
+
+func TestLowerAndRestoreLogLevel(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelError)
+
+	LowerLogLevel()
+	if GetLogLevel() != LevelWarn {
+		t.Errorf("Expected Warn after one step, got %v", GetLogLevel())
+	}
+	for i := 0; i < 5; i++ {
+		LowerLogLevel()
+	}
+	if GetLogLevel() != LevelDebug {
+		t.Errorf("Expected Debug after lowering repeatedly, got %v", GetLogLevel())
+	}
+
+	RestoreLogLevel()
+	if GetLogLevel() != LevelError {
+		t.Errorf("Expected Error after restore, got %v", GetLogLevel())
+	}
+
+	// Restoring again without a saved level must not change anything
+	RestoreLogLevel()
+	if GetLogLevel() != LevelError {
+		t.Errorf("Expected Error to remain, got %v", GetLogLevel())
+	}
+}
+
+func TestLevelHandlerRules(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	defer ClearLevelRules()
+	SetLogLevel(LevelInfo)
+	resetTestState()
+
+	body := `{"Rules":"github.com/acme/db/*=debug","TTL":"20ms"}`
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var status levelStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.Level != "info" || status.Rules == nil || *status.Rules != "github.com/acme/db/*=debug" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if !strings.Contains(getInternalOutput(), `"ToRules","github.com/acme/db/*=debug"`) {
+		t.Errorf("Expected audit entry with rules, got: %s", getInternalOutput())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for GetLevelRules() != "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if GetLevelRules() != "" {
+		t.Errorf("Expected rules to revert, got %q", GetLevelRules())
+	}
+
+	rec = httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for empty request, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package nabu
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrZstdUnsupported is reported for zstd input when nabu is built without the nabu_zstd tag.
+var ErrZstdUnsupported = errors.New("zstd input needs the nabu_zstd build tag")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Decompressor returns a reader of the decompressed content of r.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+type decompressor struct {
+	magic []byte
+	fn    Decompressor
+}
+
+// decompressors are tried in order on the first bytes of the input, protected by configMutex.
+var decompressors = []decompressor{
+	{gzipMagic, func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }},
+	{zstdMagic, newZstdReader},
+}
+
+// RegisterDecompressor makes the Parser decompress input starting with magic,
+// replacing any decompressor registered for the same magic. Gzip is registered by
+// default, and zstd when built with the nabu_zstd tag.
+func RegisterDecompressor(magic []byte, fn Decompressor) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	for i, d := range decompressors {
+		if bytes.Equal(d.magic, magic) {
+			decompressors[i].fn = fn
+			return
+		}
+	}
+	decompressors = append(decompressors, decompressor{bytes.Clone(magic), fn})
+}
+
+// Decompress makes the readers of FromReader and AddSource be decompressed when
+// they start with the magic bytes of a registered format, see RegisterDecompressor.
+// Files are always detected.
+func (p *Parser) Decompress() *Parser {
+	p.decompress = true
+	return p
+}
+
+// FromDir reads every file of dir, compressed or not, e.g. a log and its rotated
+// archives. Subdirectories and hidden files are skipped. Each file is a source
+// named after it (see AddSource), so that the entries of all files are merged by
+// date. Files are opened each time the Parser runs, all at once.
+func (p *Parser) FromDir(dir string) (*Parser, error) {
+	names, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	p.sources = nil
+	for _, name := range names {
+		p.sources = append(p.sources, &logSource{name: name, path: filepath.Join(dir, name)})
+	}
+	return p, nil
+}
+
+// AddDir adds every file of dir as a named input, like FromDir, each source being
+// named after the path of its file, e.g. to merge the directories of several services.
+func (p *Parser) AddDir(dir string) (*Parser, error) {
+	names, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		p.sources = append(p.sources, &logSource{name: path, path: path})
+	}
+	return p, nil
+}
+
+// logFiles returns the names of the files of dir read by FromDir, skipping
+// subdirectories and hidden files.
+func logFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && e.Name()[0] != '.' {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// detectCompression returns the decompressor for input starting with head, nil when
+// it is not compressed.
+func detectCompression(head []byte) Decompressor {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	for _, d := range decompressors {
+		if bytes.HasPrefix(head, d.magic) {
+			return d.fn
+		}
+	}
+	return nil
+}
+
+// magicSize is the number of bytes needed to detect any registered format.
+func magicSize() int {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	n := 0
+	for _, d := range decompressors {
+		n = max(n, len(d.magic))
+	}
+	return n
+}
+
+// fileCompression returns the decompressor for a file, nil when it is not compressed.
+func fileCompression(f *os.File) (Decompressor, error) {
+	head := make([]byte, magicSize())
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return detectCompression(head[:n]), nil
+}
+
+// peekCompression returns the decompressor for a reader, nil when it is not
+// compressed, along with a reader to use instead of r.
+func peekCompression(r io.Reader) (Decompressor, io.Reader) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(magicSize())
+	return detectCompression(head), br
+}
+
+// isCompressedFile reports whether the file at path is compressed.
+func isCompressedFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	d, _ := fileCompression(f)
+	return d != nil
+}
@@ -0,0 +1,11 @@
+//go:build !nabu_zstd
+
+package nabu
+
+import "io"
+
+// newZstdReader reports ErrZstdUnsupported, zstd support being optional to avoid
+// the dependency. Build with -tags nabu_zstd to read zstd input.
+func newZstdReader(io.Reader) (io.ReadCloser, error) {
+	return nil, ErrZstdUnsupported
+}
@@ -0,0 +1,19 @@
+//go:build !nabu_zstd
+
+package nabu
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestParserZstdUnsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.zst")
+	appendFile(t, path, string(zstdMagic)+"frame")
+
+	parsed := parseFixture(t, path, func(p *Parser) *Parser { return p })
+	if len(parsed.Errors) != 1 || !errors.Is(parsed.Errors[0].Err, ErrZstdUnsupported) {
+		t.Errorf("expected ErrZstdUnsupported, got %v", parsed.Errors)
+	}
+}
@@ -0,0 +1,179 @@
+package nabu
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var compressedLogs = entryLine("a", "01:00:00.000000", 1, "first") +
+	entryLine("", "01:00:01.000000", 2, "second") +
+	entryLine("a", "01:00:02.000000", 3, "third")
+
+func TestParserGzipFile(t *testing.T) {
+	dir := t.TempDir()
+	plain, compressed := filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.gz")
+	appendFile(t, plain, compressedLogs)
+	if err := os.WriteFile(compressed, gzipped(t, compressedLogs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := parseFixture(t, plain, func(p *Parser) *Parser { return p })
+	for _, workers := range []int{1, 4} {
+		got := parseFixture(t, compressed, func(p *Parser) *Parser { return p.Workers(workers) })
+		want.Checkpoint, got.Checkpoint = nil, nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected the gzip file to parse like the plain one, got %+v", got)
+		}
+	}
+
+	// Offsets of checkpoints are in decompressed bytes
+	c := *parseFixture(t, compressed, func(p *Parser) *Parser { return p }).Checkpoint
+	if c.Offset != int64(len(compressedLogs)) || c.Line != 3 {
+		t.Errorf("expected the decompressed offset, got %+v", c)
+	}
+	if msgs := traceMsgs(parseFixture(t, compressed, func(p *Parser) *Parser { return p.ResumeFrom(c) })); len(msgs) != 0 {
+		t.Errorf("expected nothing new, got %v", msgs)
+	}
+
+	// A new archive is read again, skipping what was seen
+	if err := os.WriteFile(compressed, gzipped(t, compressedLogs+entryLine("", "01:00:03.000000", 4, "fourth")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := traceMsgs(parseFixture(t, compressed, func(p *Parser) *Parser { return p.ResumeFrom(c) })); !reflect.DeepEqual(msgs, []string{"fourth"}) {
+		t.Errorf("expected the new entry, got %v", msgs)
+	}
+}
+
+func TestParserDecompressReader(t *testing.T) {
+	data := gzipped(t, compressedLogs)
+
+	parsed := NewParser().FromReader(bytes.NewReader(data)).Parse()
+	if len(parsed.Entries)+len(parsed.Traces) != 0 || len(parsed.Errors) == 0 {
+		t.Errorf("expected readers to be read as is by default, got %+v", parsed)
+	}
+
+	parsed = NewParser().FromReader(bytes.NewReader(data)).Decompress().Parse()
+	if msgs := traceMsgs(parsed); len(msgs) != 3 || len(parsed.Errors) != 0 {
+		t.Errorf("expected the reader to be decompressed, got %v %v", msgs, parsed.Errors)
+	}
+
+	parsed = NewParser().FromString(compressedLogs).Decompress().Parse()
+	if msgs := traceMsgs(parsed); len(msgs) != 3 {
+		t.Errorf("expected plain input to be read as is, got %v", msgs)
+	}
+
+	parsed = NewParser().FromReader(bytes.NewReader(data[:len(data)/2])).Decompress().Parse()
+	if len(parsed.Errors) != 1 || parsed.Errors[0].Line != 0 {
+		t.Errorf("expected a truncated archive to be reported, got %v", parsed.Errors)
+	}
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	defer func(saved []decompressor) { decompressors = saved }(slices.Clone(decompressors))
+
+	// A format whose content is reversed after a NABU header
+	RegisterDecompressor([]byte("NABU"), func(r io.Reader) (io.ReadCloser, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		reversed := []byte(string(data[4:]))
+		slices.Reverse(reversed)
+		return io.NopCloser(bytes.NewReader(reversed)), nil
+	})
+	content := []byte(compressedLogs)
+	slices.Reverse(content)
+	parsed := NewParser().FromReader(io.MultiReader(strings.NewReader("NABU"), bytes.NewReader(content))).Decompress().Parse()
+	if msgs := traceMsgs(parsed); len(msgs) != 3 {
+		t.Errorf("expected the registered format to be decompressed, got %v %v", msgs, parsed.Errors)
+	}
+
+	// Registering a known magic replaces its decompressor
+	RegisterDecompressor(gzipMagic, func(io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(compressedLogs[:len(compressedLogs)/2])), nil
+	})
+	if got := len(decompressors); got != 3 {
+		t.Errorf("expected 3 decompressors, got %d", got)
+	}
+}
+
+func TestParserFromDir(t *testing.T) {
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "app.log"), entryLine("b", "01:00:03.000000", 1, "current"))
+	if err := os.WriteFile(filepath.Join(dir, "app.log.1.gz"), gzipped(t, entryLine("b", "01:00:01.000000", 1, "archived")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, filepath.Join(dir, ".app.log.swp"), "binary")
+	if err := os.Mkdir(filepath.Join(dir, "old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewParser().FromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := p.Parse()
+	if len(parsed.Errors) != 0 || len(parsed.Traces) != 1 {
+		t.Fatalf("expected one trace, got %+v", parsed)
+	}
+	frames := parsed.Traces[0].Frames
+	if len(frames) != 2 || frames[0].Source != "app.log.1.gz" || frames[1].Source != "app.log" {
+		t.Errorf("expected the files to be merged by date, got %+v", frames)
+	}
+
+	if _, err := NewParser().FromDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestParserAddDir(t *testing.T) {
+	api, db := filepath.Join(t.TempDir(), "api"), filepath.Join(t.TempDir(), "db")
+	for _, dir := range []string{api, db} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendFile(t, filepath.Join(api, "app.log"), entryLine("c", "01:00:02.000000", 1, "api"))
+	appendFile(t, filepath.Join(db, "app.log"), entryLine("c", "01:00:01.000000", 1, "db"))
+	appendFile(t, filepath.Join(db, ".app.log.swp"), "binary")
+
+	p, err := NewParser().AddDir(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AddDir(db); err != nil {
+		t.Fatal(err)
+	}
+	parsed := p.Parse()
+	if len(parsed.Errors) != 0 || len(parsed.Traces) != 1 {
+		t.Fatalf("expected one trace, got %+v", parsed)
+	}
+	frames := parsed.Traces[0].Frames
+	if len(frames) != 2 || frames[0].Source != filepath.Join(db, "app.log") || frames[1].Source != filepath.Join(api, "app.log") {
+		t.Errorf("expected sources named after their paths, merged by date, got %+v", frames)
+	}
+
+	if _, err := p.AddDir(filepath.Join(api, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
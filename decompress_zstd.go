@@ -0,0 +1,18 @@
+//go:build nabu_zstd
+
+package nabu
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// newZstdReader decompresses zstd input.
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
@@ -0,0 +1,27 @@
+//go:build nabu_zstd
+
+package nabu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestParserZstdFile(t *testing.T) {
+	w, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "app.log.zst")
+	if err := os.WriteFile(path, w.EncodeAll([]byte(compressedLogs), nil), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := parseFixture(t, path, func(p *Parser) *Parser { return p })
+	if msgs := traceMsgs(parsed); len(msgs) != 3 || len(parsed.Errors) != 0 {
+		t.Errorf("expected the zstd file to be decompressed, got %v %v", msgs, parsed.Errors)
+	}
+}
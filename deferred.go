@@ -0,0 +1,64 @@
+package nabu
+
+// Boundary marks the Logger as the place where a deferred chain is written.
+// When SetDeferredChains is enabled, Log writes the whole chain as a single
+// entry instead of only recording the frame. Otherwise it has no effect.
+func (x *Logger) Boundary() *Logger {
+	x.boundary = true
+	return x
+}
+
+// Report writes the chain of err as a single entry, as a Boundary Logger would.
+// If err does not wrap a *Logger, a new chain is started from it, with the
+// caller of Report as its frame. Returns err unchanged.
+func Report(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	x := innerLogger(err)
+	if x == nil {
+		x = FromError(err)
+		x.date = getDate()
+		x.function, x.line = x.getFirstTrace()
+	}
+
+	if !shouldLogCaller(x.Level) {
+		return err
+	}
+	writeLog(x.chainOutput())
+	return err
+}
+
+// chainOutput converts the chain into a single entry.
+// The root error, joined errors and UUID are lifted to the entry itself
+// and every frame is listed in Chain, oldest first.
+func (x *Logger) chainOutput() Output {
+	o := Output{
+		UUID:      x.UUID,
+		Date:      x.date,
+		Seq:       x.seq,
+		Level:     x.Level,
+		Code:      x.code,
+		Category:  x.category,
+		Retryable: x.retryable,
+		Chain:     x.ErrorChain(),
+	}
+	if o.Date == "" {
+		o.Date = getDate()
+	}
+	for i := range o.Chain {
+		f := &o.Chain[i]
+		if o.Error == "" {
+			o.Error = f.Error
+		}
+		if o.Errors == nil {
+			o.Errors, o.Related = f.Errors, f.Related
+		}
+		if o.ParentUUID == "" {
+			o.ParentUUID = f.ParentUUID
+		}
+		f.UUID, f.Error, f.Errors, f.Related, f.ParentUUID = "", "", nil, nil, ""
+	}
+	return o
+}
@@ -0,0 +1,97 @@
+package nabu
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDeferredChainBoundary(t *testing.T) {
+	SetDeferredChains(true)
+	defer SetDeferredChains(false)
+	resetTestState()
+
+	e := a("Deferred_")
+	if getInternalOutput() != "" {
+		t.Fatalf("Expected intermediate frames not to be written, got: %s", getInternalOutput())
+	}
+
+	FromMessage("not deferred").Log()
+	FromError(e).WithMessage("handler").Boundary().Log()
+
+	lines := strings.Split(strings.TrimSpace(getInternalOutput()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %v", len(lines), lines)
+	}
+	if !strings.Contains(lines[0], "not deferred") {
+		t.Errorf("Expected message logs to be written immediately, got %s", lines[0])
+	}
+
+	entry := fromJson(lines[1])
+	if entry.Error != "testError" {
+		t.Errorf("Expected root error on the entry, got %q", entry.Error)
+	}
+	expectedMsgs := []string{"D", "C", "B", "A", "handler"}
+	if len(entry.Chain) != len(expectedMsgs) {
+		t.Fatalf("Expected %d frames, got %d", len(expectedMsgs), len(entry.Chain))
+	}
+	for i, f := range entry.Chain {
+		if f.Msg != expectedMsgs[i] {
+			t.Errorf("Frame %d: expected Msg %q, got %q", i, expectedMsgs[i], f.Msg)
+		}
+		if f.UUID != "" || f.Error != "" {
+			t.Errorf("Frame %d: expected UUID and Error only on the entry, got %+v", i, f)
+		}
+	}
+	if entry.Chain[0].Function != "github.com/rah-0/nabu.d" || entry.Chain[4].Function != "github.com/rah-0/nabu.TestDeferredChainBoundary" {
+		t.Errorf("Unexpected frame functions: %+v", entry.Chain)
+	}
+
+	parsed := NewParser().FromString(getInternalOutput()).Parse()
+	trace, ok := parsed.Trace(entry.UUID)
+	if !ok {
+		t.Fatal("Expected Parser to turn the chain into a trace")
+	}
+	if trace.Error != "testError" || len(trace.Frames) != 5 || trace.Frames[4].Msg != "handler" {
+		t.Errorf("Unexpected trace: %+v", trace)
+	}
+}
+
+func TestReport(t *testing.T) {
+	SetDeferredChains(true)
+	defer SetDeferredChains(false)
+	resetTestState()
+
+	e := a("Report_")
+	if Report(e) != e {
+		t.Error("Expected Report to return the error unchanged")
+	}
+	entry := fromJson(getInternalOutput())
+	if entry == nil || len(entry.Chain) != 4 || entry.Error != "testError" {
+		t.Fatalf("Unexpected report: %s", getInternalOutput())
+	}
+
+	resetTestState()
+	Report(errors.New("plain"))
+	entry = fromJson(getInternalOutput())
+	if entry == nil || len(entry.Chain) != 1 {
+		t.Fatalf("Unexpected report: %s", getInternalOutput())
+	}
+	if entry.Error != "plain" || entry.Chain[0].Function != "github.com/rah-0/nabu.TestReport" {
+		t.Errorf("Expected the caller of Report as frame, got %+v", entry)
+	}
+
+	if Report(nil) != nil {
+		t.Error("Expected nil for a nil error")
+	}
+}
+
+func TestBoundaryWithoutDeferredChains(t *testing.T) {
+	resetTestState()
+
+	FromError(errors.New("immediate")).Boundary().Log()
+	entry := fromJson(getInternalOutput())
+	if entry == nil || entry.Error != "immediate" || entry.Chain != nil {
+		t.Errorf("Expected a regular entry, got %s", getInternalOutput())
+	}
+}
@@ -0,0 +1,120 @@
+package nabu
+
+import (
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder renders an entry as a single line, without the line break.
+// EncodeJSON, EncodeLogfmt and FormatConsole are encoders.
+type Encoder func(o Output) string
+
+// EncodeJSON renders an entry as JSON, the format written to the configured output.
+func EncodeJSON(o Output) string {
+	return toJson(o)
+}
+
+// EncodeLogfmt renders an entry as logfmt key=value pairs, e.g.
+//
+//	date="2025-06-25 01:26:02.408736" level=error uuid=0a1f... function=main.query line=42 msg="query failed" error=EOF
+//
+// Only the fields that are set are included. Errors, Related and Args are
+// written as JSON, and static fields (see SetFields) under their own keys.
+func EncodeLogfmt(o Output) string {
+	var sb strings.Builder
+	write := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		if strings.ContainsAny(value, " =\"\t\r\n\\") {
+			value = strconv.Quote(value)
+		}
+		sb.WriteString(key + "=" + value)
+	}
+	writeJson := func(key string, v any) {
+		if v != nil {
+			write(key, consoleJson(v))
+		}
+	}
+
+	write("date", o.Date)
+	write("level", o.Level.String())
+	write("uuid", o.UUID)
+	if o.Seq != 0 {
+		write("seq", strconv.FormatUint(o.Seq, 10))
+	}
+	write("function", o.Function)
+	if o.Line != 0 {
+		write("line", strconv.Itoa(o.Line))
+	}
+	write("msg", o.Msg)
+	write("error", o.Error)
+	if len(o.Errors) > 0 {
+		writeJson("errors", o.Errors)
+	}
+	write("code", o.Code)
+	if o.Category != CategoryNone {
+		write("category", o.Category.String())
+	}
+	if o.Retryable {
+		write("retryable", "true")
+	}
+	writeJson("args", o.Args)
+	write("parent_uuid", o.ParentUUID)
+	if len(o.Related) > 0 {
+		writeJson("related", o.Related)
+	}
+	if len(o.Chain) > 0 {
+		writeJson("chain", o.Chain)
+	}
+	write("stack", o.Stack)
+	keys := make([]string, 0, len(o.Fields))
+	for k := range o.Fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if s, ok := o.Fields[k].(string); ok {
+			write(k, s)
+		} else {
+			writeJson(k, o.Fields[k])
+		}
+	}
+	return sb.String()
+}
+
+// writerSink writes the entries to an io.Writer, one line each.
+type writerSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc Encoder
+}
+
+// NewWriterSink returns a Sink writing each entry to w as a line rendered by enc,
+// e.g. NewWriterSink(os.Stderr, FormatConsole). Writes are serialized.
+func NewWriterSink(w io.Writer, enc Encoder) Sink {
+	return &writerSink{w: w, enc: enc}
+}
+
+func (s *writerSink) WriteEntry(o Output) {
+	line := s.enc(o) + "\n"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	io.WriteString(s.w, line)
+}
+
+// LevelSink returns a Sink passing to s only the entries at level min or above.
+// The configured levels (see SetLogLevel) apply before, so that it can only be stricter.
+func LevelSink(min LogLevel, s Sink) Sink {
+	return SinkFunc(func(o Output) {
+		if o.Level >= min {
+			s.WriteEntry(o)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package nabu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeLogfmt(t *testing.T) {
+	o := Output{
+		UUID:     "0a1f",
+		Date:     "2025-06-25 01:26:02.408736",
+		Error:    "EOF",
+		Args:     []any{"userID", 42},
+		Msg:      "query failed",
+		Function: "main.query",
+		Line:     42,
+		Level:    LevelError,
+		Category: CategoryUnavailable,
+		Fields:   map[string]any{"service": "api", "replica": 2},
+	}
+	expected := `date="2025-06-25 01:26:02.408736" level=error uuid=0a1f function=main.query line=42 msg="query failed" error=EOF category=unavailable args="[\"userID\",42]" replica=2 service=api`
+	if got := EncodeLogfmt(o); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if got := EncodeLogfmt(Output{Msg: "bare"}); got != "level=debug msg=bare" {
+		t.Errorf("Expected only the set fields, got %q", got)
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	remove := AddSink(LevelSink(LevelWarn, NewWriterSink(&buf, FormatConsole)))
+	defer remove()
+
+	FromMessage("ignored").Log()
+	FromMessage("careful").WithLevelWarn().Log()
+
+	if got := buf.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, " WARN careful uuid=") {
+		t.Errorf("Expected the warning only, got %q", got)
+	}
+}
@@ -0,0 +1,161 @@
+package nabu
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AfterDate keeps the entries logged strictly after threshold.
+func (p *Parser) AfterDate(threshold time.Time) *Parser {
+	p.afterDate = &threshold
+	return p
+}
+
+// BeforeDate keeps the entries logged strictly before threshold.
+func (p *Parser) BeforeDate(threshold time.Time) *Parser {
+	p.beforeDate = &threshold
+	return p
+}
+
+// Between keeps the entries logged strictly after from and strictly before to.
+func (p *Parser) Between(from, to time.Time) *Parser {
+	return p.AfterDate(from).BeforeDate(to)
+}
+
+// MinLevel keeps the entries logged at level l or above.
+func (p *Parser) MinLevel(l LogLevel) *Parser {
+	return p.Where(func(o Output) bool {
+		return o.Level >= l
+	})
+}
+
+// Levels keeps the entries logged at one of the given levels.
+func (p *Parser) Levels(levels ...LogLevel) *Parser {
+	return p.Where(func(o Output) bool {
+		return slices.Contains(levels, o.Level)
+	})
+}
+
+// UUID keeps the entries belonging to one of the given chains.
+func (p *Parser) UUID(uuids ...string) *Parser {
+	return p.Where(func(o Output) bool {
+		return slices.Contains(uuids, o.UUID)
+	})
+}
+
+// FunctionMatches keeps the entries whose Function matches re.
+func (p *Parser) FunctionMatches(re *regexp.Regexp) *Parser {
+	return p.Where(func(o Output) bool {
+		return re.MatchString(o.Function)
+	})
+}
+
+// MessageContains keeps the entries whose Msg contains substr.
+func (p *Parser) MessageContains(substr string) *Parser {
+	return p.Where(func(o Output) bool {
+		return strings.Contains(o.Msg, substr)
+	})
+}
+
+// ErrorMatches keeps the entries whose Error, or one of whose Errors, matches re.
+// Since the error is only written on the first frame of a chain, combine it with
+// KeepWholeTraces to get the rest of the chain.
+func (p *Parser) ErrorMatches(re *regexp.Regexp) *Parser {
+	return p.Where(func(o Output) bool {
+		return (o.Error != "" && re.MatchString(o.Error)) || slices.ContainsFunc(o.Errors, re.MatchString)
+	})
+}
+
+// WhereArg keeps the entries having an argument named key whose value satisfies
+// match. See ArgValue for how arguments are looked up.
+func (p *Parser) WhereArg(key string, match func(v any) bool) *Parser {
+	return p.Where(func(o Output) bool {
+		v, ok := ArgValue(o.Args, key)
+		return ok && match(v)
+	})
+}
+
+// WhereField keeps the entries having a static field named key (see SetFields)
+// whose value satisfies match. Values are decoded from JSON, so numbers are float64.
+func (p *Parser) WhereField(key string, match func(v any) bool) *Parser {
+	return p.Where(func(o Output) bool {
+		v, ok := o.Fields[key]
+		return ok && match(v)
+	})
+}
+
+// Where keeps the entries satisfying match.
+func (p *Parser) Where(match func(o Output) bool) *Parser {
+	p.filters = append(p.filters, match)
+	return p
+}
+
+// KeepWholeTraces keeps every frame of a trace as soon as one of them matches
+// the filters, instead of only the matching frames.
+func (p *Parser) KeepWholeTraces() *Parser {
+	p.keepWholeTraces = true
+	return p
+}
+
+// ArgValue looks up the argument named key in the Args of a parsed entry.
+// Args logged as key/value pairs (WithArgs("userID", 42)) and as a map or struct
+// (WithArgs(map[string]any{"userID": 42})) are both supported. Values are
+// decoded from JSON, so numbers are float64.
+func ArgValue(args any, key string) (any, bool) {
+	switch a := args.(type) {
+	case map[string]any:
+		v, ok := a[key]
+		return v, ok
+	case []any:
+		if len(a) == 1 {
+			return ArgValue(a[0], key)
+		}
+		for i := 0; i+1 < len(a); i += 2 {
+			if k, ok := a[i].(string); ok && k == key {
+				return a[i+1], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ArgEquals returns a WhereArg or WhereField predicate comparing the text of a
+// value with s, e.g. WhereArg("userID", ArgEquals("42")).
+func ArgEquals(s string) func(v any) bool {
+	return func(v any) bool {
+		return fmt.Sprint(v) == s
+	}
+}
+
+// matches reports whether the entry satisfies every filter.
+func (p *Parser) matches(o Output) bool {
+	f := dated{Output: o}
+	if p.afterDate != nil || p.beforeDate != nil {
+		f = newDated(o)
+	}
+	return p.matchesDated(f)
+}
+
+// matchesDated is matches for an entry whose date is already parsed.
+func (p *Parser) matchesDated(f dated) bool {
+	if p.afterDate != nil || p.beforeDate != nil {
+		if f.date.IsZero() {
+			return false
+		}
+		if p.afterDate != nil && !f.date.After(*p.afterDate) {
+			return false
+		}
+		if p.beforeDate != nil && !f.date.Before(*p.beforeDate) {
+			return false
+		}
+	}
+	for _, match := range p.filters {
+		if !match(f.Output) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package nabu
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var filterLogs = []string{
+	`{"Date":"2025-06-25 01:00:00.000000","Msg":"started","Function":"main.run","Line":3,"Level":1}`,
+	`{"UUID":"a","Date":"2025-06-25 01:00:01.000000","Error":"connection refused","Args":["userID",42],"Fields":{"service":"api"},"Msg":"query failed","Function":"db.Query","Line":9,"Level":3}`,
+	`{"UUID":"a","Date":"2025-06-25 01:00:02.000000","Msg":"request failed","Function":"http.handle","Line":5,"Level":3}`,
+	`{"UUID":"b","Date":"2025-06-25 01:00:03.000000","Error":"timeout","Args":[{"userID":7}],"Msg":"fetch failed","Function":"http.fetch","Line":12,"Level":2}`,
+	`{"Date":"2025-06-25 01:00:04.000000","Args":["userID",42],"Fields":{"service":"worker"},"Msg":"retrying","Function":"main.run","Line":8,"Level":0}`,
+}
+
+func TestParserFilters(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(TimeLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	cases := []struct {
+		name   string
+		parser *Parser
+		want   []string // Messages of the entries and frames kept, entries first
+	}{
+		{"No filter", NewParser(), []string{"started", "retrying", "query failed", "request failed", "fetch failed"}},
+		{"BeforeDate", NewParser().BeforeDate(date("2025-06-25 01:00:02.000000")), []string{"started", "query failed"}},
+		{"Between", NewParser().Between(date("2025-06-25 01:00:01.000000"), date("2025-06-25 01:00:04.000000")), []string{"request failed", "fetch failed"}},
+		{"MinLevel", NewParser().MinLevel(LevelError), []string{"query failed", "request failed"}},
+		{"Levels", NewParser().Levels(LevelDebug, LevelWarn), []string{"retrying", "fetch failed"}},
+		{"UUID", NewParser().UUID("b"), []string{"fetch failed"}},
+		{"FunctionMatches", NewParser().FunctionMatches(regexp.MustCompile(`^http\.`)), []string{"request failed", "fetch failed"}},
+		{"MessageContains", NewParser().MessageContains("failed").MinLevel(LevelWarn).BeforeDate(date("2025-06-25 01:00:03.000000")), []string{"query failed", "request failed"}},
+		{"ErrorMatches", NewParser().ErrorMatches(regexp.MustCompile(`refused`)), []string{"query failed"}},
+		{"ErrorMatches whole trace", NewParser().ErrorMatches(regexp.MustCompile(`refused`)).KeepWholeTraces(), []string{"query failed", "request failed"}},
+		{"WhereArg pairs", NewParser().WhereArg("userID", ArgEquals("42")), []string{"retrying", "query failed"}},
+		{"WhereArg map", NewParser().WhereArg("userID", ArgEquals("7")), []string{"fetch failed"}},
+		{"WhereField", NewParser().WhereField("service", ArgEquals("worker")), []string{"retrying"}},
+		{"WhereField not args", NewParser().WhereField("userID", ArgEquals("42")), nil},
+		{"Where", NewParser().Where(func(o Output) bool { return o.Line > 8 }), []string{"query failed", "fetch failed"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parsed := c.parser.FromLines(filterLogs).Parse()
+			var got []string
+			for _, e := range parsed.Entries {
+				got = append(got, e.Msg)
+			}
+			for _, trace := range parsed.Traces {
+				for _, f := range trace.Frames {
+					got = append(got, f.Msg)
+				}
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expected %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestParserFiltersKeepTraceError(t *testing.T) {
+	parsed := NewParser().FromLines(filterLogs).MessageContains("request").Parse()
+	if len(parsed.Traces) != 1 || parsed.Traces[0].Error != "connection refused" {
+		t.Errorf("expected the trace error to be kept, got %+v", parsed.Traces)
+	}
+}
+
+func TestParserFiltersStreaming(t *testing.T) {
+	var msgs []string
+	for entry, err := range NewParser().FromLines(filterLogs).MinLevel(LevelWarn).Entries() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, entry.Msg)
+	}
+	if !reflect.DeepEqual(msgs, []string{"query failed", "request failed", "fetch failed"}) {
+		t.Errorf("unexpected entries %v", msgs)
+	}
+
+	var uuids []string
+	for trace, err := range NewParser().FromLines(filterLogs).UUID("b").Traces(time.Minute) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, trace.UUID)
+	}
+	if !reflect.DeepEqual(uuids, []string{"b"}) {
+		t.Errorf("expected only trace b, got %v", uuids)
+	}
+}
+
+func TestArgValue(t *testing.T) {
+	cases := []struct {
+		args  any
+		value any
+		found bool
+	}{
+		{[]any{"userID", 42.0, "name", "alice"}, 42.0, true},
+		{[]any{"name", "alice", "userID"}, nil, false},
+		{[]any{map[string]any{"userID": "x"}}, "x", true},
+		{map[string]any{"userID": true}, true, true},
+		{"userID", nil, false},
+		{nil, nil, false},
+	}
+	for _, c := range cases {
+		v, ok := ArgValue(c.args, "userID")
+		if v != c.value || ok != c.found {
+			t.Errorf("ArgValue(%v): expected %v %v, got %v %v", c.args, c.value, c.found, v, ok)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package nabu
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"time"
+)
+
+// DefaultFollowInterval is how often a followed file is checked for new lines
+// unless FollowOptions.Interval is set.
+const DefaultFollowInterval = 250 * time.Millisecond
+
+// FollowStart selects where Follow starts reading.
+type FollowStart int
+
+const (
+	// FollowFromEnd only reads lines appended after Follow started, like tail -f
+	FollowFromEnd FollowStart = iota
+	// FollowFromStart reads the whole file first, e.g. combined with AfterDate
+	FollowFromStart
+	// FollowFromOffset starts at FollowOptions.Offset, e.g. where a previous run stopped
+	FollowFromOffset
+)
+
+// FollowOptions configures Follow and FollowTraces.
+type FollowOptions struct {
+	Start    FollowStart
+	Offset   int64         // Byte offset to start from, with FollowFromOffset
+	Interval time.Duration // How often to check for new lines, DefaultFollowInterval when zero
+}
+
+// Follow reads the file at path like tail -F: it keeps waiting for appended lines
+// until ctx is done, and reopens the file when it is replaced (rename-based rotation)
+// or reads it again from the start when it is truncated. Lines still in the old file
+// when it is rotated are read first. Entries are yielded as with Entries, filters
+// included. The line numbers of errors are counted from where reading started,
+// and from the start of the file after a rotation or truncation.
+// An error opening the file is yielded and ends the iteration; while the file is
+// missing after a rotation, Follow keeps waiting for it.
+func (p *Parser) Follow(ctx context.Context, path string, opts FollowOptions) iter.Seq2[Output, error] {
+	return func(yield func(Output, error) bool) {
+		p.follow(ctx, path, opts, nil, func(entry dated, err error) bool {
+			if err == nil && !slices.ContainsFunc(expandDated(entry), p.matchesDated) {
+				return true
+			}
+			return yield(entry.Output, err)
+		})
+	}
+}
+
+// FollowTraces follows the file at path like Follow and assembles traces like Traces.
+// A trace is emitted once no entry with its UUID has been seen for the quiet window,
+// measured with the dates of the entries, or with the current time while no line
+// is appended. Traces still open when ctx is done are not emitted.
+func (p *Parser) FollowTraces(ctx context.Context, path string, quiet time.Duration, opts FollowOptions) iter.Seq2[ParsedErrorTrace, error] {
+	return func(yield func(ParsedErrorTrace, error) bool) {
+		w := newTraceWindow(quiet)
+		idle := func() bool {
+			now := time.Now().UTC() // Dates are written in UTC
+			return w.flush(&now, yield)
+		}
+		p.follow(ctx, path, opts, idle, func(entry dated, err error) bool {
+			if err != nil {
+				return yield(ParsedErrorTrace{}, err)
+			}
+			return p.addToWindow(w, entry, yield)
+		})
+	}
+}
+
+// follower reads the lines of a followed file.
+type follower struct {
+	path    string
+	f       *os.File
+	info    os.FileInfo // Of f, to detect that path was replaced
+	br      *bufio.Reader
+	offset  int64  // Bytes of f consumed so far
+	buf     []byte // Line being read, possibly incomplete
+	tooLong bool   // Whether the line being read exceeds the limit
+	lineNo  int    // Lines read since the start, rotation or truncation
+}
+
+// follow calls fn with each decoded entry or rejected line until ctx is done or fn
+// returns false. idle, if set, is called while waiting for lines and stops following
+// when it returns false.
+func (p *Parser) follow(ctx context.Context, path string, opts FollowOptions, idle func() bool, fn func(dated, error) bool) {
+	fl := &follower{path: path}
+	if err := fl.open(opts.Start, opts.Offset); err != nil {
+		fn(dated{}, &ParseError{Source: path, Err: err})
+		return
+	}
+	defer func() { fl.f.Close() }() // The file changes on rotation
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultFollowInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	limit := p.lineLimit()
+	for {
+		line, tooLong, err := fl.readLine(limit)
+		if err == io.EOF {
+			if idle != nil && !idle() {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if pending, rotated := fl.checkFile(); rotated {
+				if pending != "" && !p.followLine(fl, pending, false, fn) {
+					return
+				}
+				fl.lineNo = 0
+			}
+			continue
+		}
+		if err != nil {
+			fn(dated{}, &ParseError{Source: path, Err: err})
+			return
+		}
+		if !p.followLine(fl, line, tooLong, fn) {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// followLine decodes a line and passes it to fn, reporting false to stop following.
+func (p *Parser) followLine(fl *follower, line string, tooLong bool, fn func(dated, error) bool) bool {
+	fl.lineNo++
+	entry, ok, err := p.decodeLine(fl.path, fl.lineNo, line, tooLong)
+	if !ok {
+		return true
+	}
+	if err != nil {
+		return fn(dated{}, err) && !p.stopsAt(err)
+	}
+	return fn(entry, nil)
+}
+
+// open opens the file and moves to the start position.
+func (fl *follower) open(start FollowStart, offset int64) error {
+	f, err := os.Open(fl.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	switch start {
+	case FollowFromEnd:
+		offset = info.Size()
+	case FollowFromStart:
+		offset = 0
+	}
+	if offset > info.Size() {
+		offset = 0 // The file was truncated since the offset was saved
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	fl.f, fl.info, fl.offset = f, info, offset
+	fl.br = bufio.NewReader(f)
+	fl.buf, fl.tooLong = fl.buf[:0], false
+	return nil
+}
+
+// readLine returns the next complete line, or io.EOF when the rest of the line has
+// not been written yet. A partial line is kept until it is completed.
+func (fl *follower) readLine(limit int) (string, bool, error) {
+	for {
+		chunk, err := fl.br.ReadSlice('\n')
+		fl.offset += int64(len(chunk))
+		if room := limit - len(fl.buf); len(chunk) > room {
+			fl.buf = append(fl.buf, chunk[:room]...)
+			fl.tooLong = true
+		} else {
+			fl.buf = append(fl.buf, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+
+		line := string(fl.buf)
+		tooLong := fl.tooLong && len(trimNewline(line)) >= limit
+		fl.buf, fl.tooLong = fl.buf[:0], false
+		return trimNewline(line), tooLong, nil
+	}
+}
+
+// checkFile handles the rotation or truncation of the file while waiting for lines.
+// It reports whether the file was rotated, with the partial line left in the old
+// file, since nothing will complete it.
+func (fl *follower) checkFile() (string, bool) {
+	info, err := os.Stat(fl.path)
+	if err != nil {
+		return "", false // Rotation in progress, the new file is not there yet
+	}
+
+	if !os.SameFile(info, fl.info) {
+		if current, err := fl.f.Stat(); err == nil && current.Size() > fl.offset {
+			return "", false // Read what was written to the old file before switching
+		}
+		pending := trimNewline(string(fl.buf))
+		old := fl.f
+		if err := fl.open(FollowFromStart, 0); err != nil {
+			return "", false
+		}
+		old.Close()
+		return pending, true
+	}
+
+	if info.Size() < fl.offset {
+		fl.f.Seek(0, io.SeekStart)
+		fl.br.Reset(fl.f)
+		fl.offset, fl.lineNo = 0, 0
+		fl.buf, fl.tooLong = fl.buf[:0], false
+	}
+	return "", false
+}
+
+func trimNewline(line string) string {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
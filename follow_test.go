@@ -0,0 +1,171 @@
+package nabu
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func followLine(msg, uuid, date string) string {
+	return `{"UUID":"` + uuid + `","Date":"2025-06-25 ` + date + `","Msg":"` + msg + `","Level":3}` + "\n"
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectMsgs reads n entries from the channel and compares their messages.
+func expectMsgs(t *testing.T, entries <-chan Output, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case e := <-entries:
+			if e.Msg != w {
+				t.Fatalf("expected %q, got %q", w, e.Msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", w)
+		}
+	}
+}
+
+func TestParserFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, followLine("old", "", "01:00:00.000000"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries := make(chan Output, 10)
+	errs := make(chan error, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for entry, err := range NewParser().MinLevel(LevelWarn).Follow(ctx, path, FollowOptions{Interval: 5 * time.Millisecond}) {
+			if err != nil {
+				errs <- err
+				continue
+			}
+			entries <- entry
+		}
+	}()
+	time.Sleep(50 * time.Millisecond) // Let Follow reach the end of the file
+
+	appendFile(t, path, followLine("first", "a", "01:00:01.000000"))
+	expectMsgs(t, entries, "first")
+
+	// A line written in two parts is only decoded once complete
+	line := followLine("split", "a", "01:00:02.000000")
+	appendFile(t, path, line[:20])
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, line[20:]+`{"Msg":"debug","Level":0}`+"\n")
+	expectMsgs(t, entries, "split")
+
+	// Rename-based rotation: the rest of the old file is read, then the new file
+	old := filepath.Join(filepath.Dir(path), "app.log.1")
+	if err := os.Rename(path, old); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, old, followLine("before rotation", "", "01:00:03.000000"))
+	appendFile(t, path, followLine("after rotation", "", "01:00:04.000000"))
+	expectMsgs(t, entries, "before rotation", "after rotation")
+
+	// Truncation: the file is read again from the start
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "broken\n"+followLine("after truncation", "", "01:00:05.000000"))
+	expectMsgs(t, entries, "after truncation")
+	select {
+	case err := <-errs:
+		if pe, ok := err.(*ParseError); !ok || pe.Line != 1 {
+			t.Errorf("expected a ParseError on line 1, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the broken line to be reported")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Follow to stop when the context is done")
+	}
+}
+
+func TestParserFollowStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	first := followLine("first", "", "01:00:00.000000")
+	appendFile(t, path, first+followLine("second", "", "01:00:01.000000"))
+
+	collect := func(p *Parser, opts FollowOptions) []string {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		opts.Interval = 5 * time.Millisecond
+		var msgs []string
+		for entry, err := range p.Follow(ctx, path, opts) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			msgs = append(msgs, entry.Msg)
+		}
+		return msgs
+	}
+
+	if msgs := collect(NewParser(), FollowOptions{Start: FollowFromStart}); len(msgs) != 2 {
+		t.Errorf("expected the whole file, got %v", msgs)
+	}
+	if msgs := collect(NewParser(), FollowOptions{Start: FollowFromOffset, Offset: int64(len(first))}); len(msgs) != 1 || msgs[0] != "second" {
+		t.Errorf("expected to start at the offset, got %v", msgs)
+	}
+	after, _ := time.Parse(TimeLayout, "2025-06-25 01:00:00.500000")
+	if msgs := collect(NewParser().AfterDate(after), FollowOptions{Start: FollowFromStart}); len(msgs) != 1 || msgs[0] != "second" {
+		t.Errorf("expected AfterDate to apply, got %v", msgs)
+	}
+	if msgs := collect(NewParser(), FollowOptions{}); len(msgs) != 0 {
+		t.Errorf("expected nothing from the end, got %v", msgs)
+	}
+
+	for _, err := range NewParser().Follow(context.Background(), filepath.Join(t.TempDir(), "missing.log"), FollowOptions{}) {
+		if err == nil || !os.IsNotExist(err.(*ParseError).Err) {
+			t.Errorf("expected a not exist error, got %v", err)
+		}
+	}
+}
+
+func TestParserFollowTraces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Now().UTC()
+	date := func(d time.Duration) string {
+		return now.Add(d).Format(TimeLayout)
+	}
+	appendFile(t, path, `{"UUID":"a","Date":"`+date(-time.Hour)+`","Error":"EOF","Level":3}`+"\n"+
+		`{"UUID":"b","Date":"`+date(0)+`","Error":"timeout","Level":3}`+"\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var uuids []string
+	for trace, err := range NewParser().FollowTraces(ctx, path, 200*time.Millisecond, FollowOptions{Start: FollowFromStart, Interval: 5 * time.Millisecond}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, trace.UUID)
+		if len(uuids) == 2 {
+			break
+		}
+	}
+	// a is emitted when b arrives, b once it has been quiet for a while
+	if len(uuids) != 2 || uuids[0] != "a" || uuids[1] != "b" {
+		t.Errorf("expected traces a then b, got %v", uuids)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/rah-0/nabu
 
 go 1.25.3
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.18.0
+)
@@ -0,0 +1,138 @@
+package nabu
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxGroupExamples is the number of example UUIDs kept per ErrorGroup.
+const maxGroupExamples = 5
+
+var (
+	uuidPattern   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	hexPattern    = regexp.MustCompile(`\b(0x[0-9a-fA-F]+|[0-9a-fA-F]{8,})\b`)
+	numberPattern = regexp.MustCompile(`\b\d+(\.\d+)?`)
+)
+
+// ErrorGroup gathers the traces that share a fingerprint, i.e. the same error
+// raised along the same path, whatever the values in its message.
+type ErrorGroup struct {
+	Fingerprint string
+	Error       string    // Root error of the traces, normalized
+	Functions   []string  // Functions of the frames, oldest first
+	Count       int       // Number of traces
+	FirstSeen   time.Time // Date of the first frame of the oldest trace
+	LastSeen    time.Time // Date of the last frame of the newest trace
+	UUIDs       []string  // Up to 5 example traces, in order of appearance
+	ArgKeys     []string  // Keys of the Args logged by any frame, sorted
+}
+
+// Fingerprint identifies the kind of error of a trace. It is derived from the
+// root error, with numbers, UUIDs and hexadecimal identifiers replaced (see
+// NormalizeError), and from the functions of the frames in order, so that the
+// same failure gets the same fingerprint across UUIDs, processes and files.
+func (t ParsedErrorTrace) Fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte(NormalizeError(errorKey(t.Error, t.Errors))))
+	for _, f := range t.Frames {
+		h.Write([]byte{0})
+		h.Write([]byte(f.Function))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// NormalizeError replaces the parts of an error message that vary between
+// occurrences, e.g. `user 42 not found (id 5f1c...)` becomes `user <n> not found (id <uuid>)`.
+// UUIDs become <uuid>, hexadecimal identifiers <hex> and numbers <n>, except digits
+// ending a word such as utf8 or sha256.
+func NormalizeError(msg string) string {
+	msg = uuidPattern.ReplaceAllString(msg, "<uuid>")
+	msg = hexPattern.ReplaceAllStringFunc(msg, func(id string) string {
+		if strings.HasPrefix(id, "0x") || strings.ContainsAny(id, "0123456789") {
+			return "<hex>"
+		}
+		return id // A word such as "deadbeef" or "facade"
+	})
+	return numberPattern.ReplaceAllString(msg, "<n>")
+}
+
+// Groups gathers the traces carrying an error by Fingerprint, like issues in an
+// error tracker. Groups are ordered by decreasing count, then by first appearance.
+func (p ParsedLogs) Groups() []ErrorGroup {
+	var groups []*ErrorGroup
+	byFingerprint := make(map[string]*ErrorGroup)
+
+	for _, t := range p.Traces {
+		key := errorKey(t.Error, t.Errors)
+		if key == "" {
+			continue
+		}
+		fp := t.Fingerprint()
+		g, ok := byFingerprint[fp]
+		if !ok {
+			g = &ErrorGroup{Fingerprint: fp, Error: NormalizeError(key)}
+			for _, f := range t.Frames {
+				g.Functions = append(g.Functions, f.Function)
+			}
+			byFingerprint[fp] = g
+			groups = append(groups, g)
+		}
+
+		g.Count++
+		if len(g.UUIDs) < maxGroupExamples {
+			g.UUIDs = append(g.UUIDs, t.UUID)
+		}
+		for _, f := range t.Frames {
+			g.ArgKeys = appendUnique(g.ArgKeys, argKeys(f.Args)...)
+			d, err := time.Parse(TimeLayout, f.Date)
+			if err != nil {
+				continue
+			}
+			if g.FirstSeen.IsZero() || d.Before(g.FirstSeen) {
+				g.FirstSeen = d
+			}
+			if d.After(g.LastSeen) {
+				g.LastSeen = d
+			}
+		}
+	}
+
+	result := make([]ErrorGroup, len(groups))
+	for i, g := range groups {
+		slices.Sort(g.ArgKeys)
+		result[i] = *g
+	}
+	slices.SortStableFunc(result, func(a, b ErrorGroup) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return result
+}
+
+// argKeys returns the keys of Args, looked up like ArgValue.
+func argKeys(args any) []string {
+	switch a := args.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(a))
+		for k := range a {
+			keys = append(keys, k)
+		}
+		return keys
+	case []any:
+		if len(a) == 1 {
+			return argKeys(a[0])
+		}
+		var keys []string
+		for i := 0; i+1 < len(a); i += 2 {
+			if k, ok := a[i].(string); ok {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package nabu
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeError(t *testing.T) {
+	cases := map[string]string{
+		"user 42 not found": "user <n> not found",
+		"order 5f1c2d3e-aaaa-4bbb-8ccc-0123456789ab failed":   "order <uuid> failed",
+		"bad pointer 0x1f at offset 3.5":                      "bad pointer <hex> at offset <n>",
+		"object 9f86d081884c7d659a2feaa0 missing":             "object <hex> missing",
+		"deadbeefcafe stays a word, as does utf8":             "deadbeefcafe stays a word, as does utf8",
+		"sha256 mismatch after 30s in base64 part 2":          "sha256 mismatch after <n>s in base64 part <n>",
+		"dial tcp 10.0.0.1:5432: connect: connection refused": "dial tcp <n>.<n>:<n>: connect: connection refused",
+	}
+	for msg, want := range cases {
+		if got := NormalizeError(msg); got != want {
+			t.Errorf("NormalizeError(%q): expected %q, got %q", msg, want, got)
+		}
+	}
+}
+
+func TestParsedLogsGroups(t *testing.T) {
+	logs := []string{
+		`{"UUID":"a","Date":"2025-06-25 01:00:00.000000","Error":"user 1 not found","Args":["userID",1],"Function":"db.Get","Level":3}`,
+		`{"UUID":"a","Date":"2025-06-25 01:00:01.000000","Args":{"route":"/users"},"Function":"http.handle","Level":3}`,
+		`{"UUID":"b","Date":"2025-06-25 01:00:02.000000","Error":"timeout","Function":"db.Get","Level":3}`,
+		`{"UUID":"c","Date":"2025-06-25 01:00:03.000000","Error":"user 2 not found","Args":["userID",2,"tenant","x"],"Function":"db.Get","Level":3}`,
+		`{"UUID":"c","Date":"2025-06-25 01:00:05.000000","Function":"http.handle","Level":3}`,
+		`{"UUID":"d","Date":"2025-06-25 01:00:06.000000","Error":"user 3 not found","Function":"db.Get","Level":3}`,
+		`{"UUID":"e","Date":"2025-06-25 01:00:07.000000","Msg":"no error","Level":1}`,
+	}
+	parsed := NewParser().FromLines(logs).Parse()
+	groups := parsed.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %+v", groups)
+	}
+
+	date := func(s string) time.Time {
+		d, _ := time.Parse(TimeLayout, "2025-06-25 01:00:0"+s+".000000")
+		return d
+	}
+	want := ErrorGroup{
+		Fingerprint: groups[0].Fingerprint,
+		Error:       "user <n> not found",
+		Functions:   []string{"db.Get", "http.handle"},
+		Count:       2,
+		FirstSeen:   date("0"),
+		LastSeen:    date("5"),
+		UUIDs:       []string{"a", "c"},
+		ArgKeys:     []string{"route", "tenant", "userID"},
+	}
+	if !reflect.DeepEqual(groups[0], want) {
+		t.Errorf("expected %+v, got %+v", want, groups[0])
+	}
+	if groups[1].Error != "timeout" || groups[2].UUIDs[0] != "d" {
+		t.Errorf("expected the other groups by first appearance, got %+v", groups[1:])
+	}
+
+	a, _ := parsed.Trace("a")
+	d, _ := parsed.Trace("d")
+	if a.Fingerprint() != groups[0].Fingerprint || a.Fingerprint() == d.Fingerprint() {
+		t.Error("expected the fingerprint to depend on the functions of the frames")
+	}
+}
@@ -0,0 +1,39 @@
+package nabu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// levelNames maps each LogLevel to its textual representation.
+var levelNames = map[LogLevel]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+	LevelFatal: "fatal",
+}
+
+// String returns the lowercase name of the level (e.g. "warn").
+// Unknown levels are rendered as their numeric value.
+func (l LogLevel) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLevel converts a level name into a LogLevel.
+// Matching is case-insensitive and accepts "warning" as an alias of "warn".
+func ParseLevel(s string) (LogLevel, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "warning" {
+		name = "warn"
+	}
+	for l, n := range levelNames {
+		if n == name {
+			return l, nil
+		}
+	}
+	return LevelDebug, fmt.Errorf("nabu: unknown log level %q", s)
+}
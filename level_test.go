@@ -0,0 +1,46 @@
+package nabu
+
+import (
+	"testing"
+)
+
+func TestLevelString(t *testing.T) {
+	cases := map[LogLevel]string{
+		LevelDebug:   "debug",
+		LevelInfo:    "info",
+		LevelWarn:    "warn",
+		LevelError:   "error",
+		LevelFatal:   "fatal",
+		LogLevel(42): "LogLevel(42)",
+	}
+	for l, expected := range cases {
+		if l.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, l.String())
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]LogLevel{
+		"debug":   LevelDebug,
+		"INFO":    LevelInfo,
+		"warn":    LevelWarn,
+		"Warning": LevelWarn,
+		" error ": LevelError,
+		"fatal":   LevelFatal,
+	}
+	for s, expected := range cases {
+		l, err := ParseLevel(s)
+		if err != nil {
+			t.Errorf("ParseLevel(%q): unexpected error: %v", s, err)
+			continue
+		}
+		if l != expected {
+			t.Errorf("ParseLevel(%q): expected %v, got %v", s, expected, l)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
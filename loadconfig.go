@@ -0,0 +1,831 @@
+package nabu
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv, in addition to EnvLevel.
+const (
+	// EnvOutput selects the output, e.g. "stdout"
+	EnvOutput = "NABU_OUTPUT"
+	// EnvTimeFormat is the layout of dates, see SetTimeFormat
+	EnvTimeFormat = "NABU_TIME_FORMAT"
+	// EnvFields lists static fields as key=value pairs, e.g. "service=api,env=prod"
+	EnvFields = "NABU_FIELDS"
+)
+
+// Config describes the complete logging setup.
+// It can be built in code, read from a JSON or YAML file, or from the environment.
+type Config struct {
+	Level      string                // Minimum level, e.g. "info" (empty means LevelDebug)
+	Rules      map[string]string     // Per-package overrides, pattern to level (see SetLevelRules)
+	Output     string                // "stderr" (default), "stdout", "internal" or "none"
+	Sinks      map[string]SinkConfig // Additional destinations by name
+	Sampling   Sampling              // Sampling of repetitive entries (see SetSampling)
+	Redaction  RedactionConfig       // Values hidden from entries (see SetRedaction)
+	Fields     map[string]any        // Static fields added to every entry (see SetFields)
+	TimeFormat string                // Layout of dates, e.g. "2006-01-02T15:04:05Z07:00" (see SetTimeFormat)
+}
+
+// SinkConfig describes a destination of the entries, in addition to the output.
+type SinkConfig struct {
+	Type     string   // "file" (default), "stdout" or "stderr"
+	Path     string   // File written by file sinks
+	Encoder  string   // "json" (default), "logfmt" or "console" (see Encoder)
+	Level    string   // Minimum level of the entries written to the sink (see LevelSink)
+	Rotation Rotation // Rotation of file sinks; MaxSize accepts "10MB" in files
+}
+
+// RedactionConfig describes a Redaction, with patterns as regular expressions.
+type RedactionConfig struct {
+	Keys     []string          // Names of Args whose values are hidden; a comma-separated list in YAML
+	Patterns map[string]string // Regular expressions hidden in messages, errors and arguments, by name
+}
+
+// ConfigError reports an invalid configuration value along with the offending key.
+type ConfigError struct {
+	Key string // Path of the offending key as written in files, e.g. "rules.github.com/acme/db/*"
+	Err error  // What is wrong with it
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("nabu: config key %q: %s", e.Key, strings.TrimPrefix(e.Err.Error(), "nabu: "))
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnknownConfigKey is reported for keys nabu does not recognize
+var ErrUnknownConfigKey = errors.New("unknown key")
+
+var (
+	// configSinks are the sinks installed by ApplyConfig, replaced by the next call
+	configSinks []*sinkEntry
+
+	// configFiles are the files written by configSinks, closed once replaced
+	configFiles []*FileSink
+)
+
+// LoadConfig reads a JSON or YAML configuration file and applies it.
+// Nothing is applied if the file contains any invalid key.
+func LoadConfig(path string) error {
+	c, err := ReadConfig(path)
+	if err != nil {
+		return err
+	}
+	return ApplyConfig(c)
+}
+
+// ReadConfig reads and validates a configuration file without applying it.
+// Files ending in .json are parsed as JSON, .yaml and .yml as YAML;
+// any other file is parsed as JSON if it starts with '{' and as YAML otherwise.
+// Only the YAML subset needed by Config is supported: nested mappings and scalars.
+func ReadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var m map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &m)
+	case ".yaml", ".yml":
+		m, err = parseYAML(data)
+	default:
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			err = json.Unmarshal(data, &m)
+		} else {
+			m, err = parseYAML(data)
+		}
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("nabu: parsing %s: %w", path, err)
+	}
+	return configFromMap(m)
+}
+
+// ConfigFromEnv builds a Config from NABU_LEVEL, NABU_OUTPUT, NABU_TIME_FORMAT and NABU_FIELDS.
+// NABU_LEVEL uses the syntax of SetLevelRules, e.g. "info,github.com/acme/db/*=debug".
+// Sinks, sampling and redaction are only read from files.
+func ConfigFromEnv() (Config, error) {
+	var c Config
+	if spec := os.Getenv(EnvLevel); spec != "" {
+		global, rules, err := parseLevelSpec(spec)
+		if err != nil {
+			return Config{}, &ConfigError{Key: EnvLevel, Err: err}
+		}
+		if global != nil {
+			c.Level = global.String()
+		}
+		if len(rules) > 0 {
+			c.Rules = make(map[string]string, len(rules))
+			for _, r := range rules {
+				c.Rules[r.pattern] = r.level.String()
+			}
+		}
+	}
+	if o := os.Getenv(EnvOutput); o != "" {
+		if _, err := parseOutput(o); err != nil {
+			return Config{}, &ConfigError{Key: EnvOutput, Err: err}
+		}
+		c.Output = o
+	}
+	if layout := os.Getenv(EnvTimeFormat); layout != "" {
+		if err := checkTimeFormat(layout); err != nil {
+			return Config{}, &ConfigError{Key: EnvTimeFormat, Err: err}
+		}
+		c.TimeFormat = layout
+	}
+	if spec := os.Getenv(EnvFields); spec != "" {
+		c.Fields = make(map[string]any)
+		for _, entry := range strings.Split(spec, ",") {
+			k, v, found := strings.Cut(entry, "=")
+			if k = strings.TrimSpace(k); !found || k == "" {
+				return Config{}, &ConfigError{Key: EnvFields, Err: fmt.Errorf("expected key=value, got %q", entry)}
+			}
+			c.Fields[k] = strings.TrimSpace(v)
+		}
+	}
+	return c, nil
+}
+
+// ApplyConfig validates a Config and installs it as the complete setup.
+// Empty fields fall back to the defaults, rules not present in c are removed and
+// the sinks of the previous Config are closed, so applying a reloaded file always
+// yields the same state as a fresh start. Sinks registered with AddSink are kept.
+// Like SetLogLevel, it cancels any pending SetLogLevelFor revert, and a change of
+// the levels is logged as an audit entry.
+func ApplyConfig(c Config) error {
+	r, err := c.resolve()
+	if err != nil {
+		return err
+	}
+	opened, files, err := r.openSinks()
+	if err != nil {
+		return err
+	}
+
+	configMutex.Lock()
+	previous := levelState{level: logLevel, rules: levelRules}
+	applyLevelStateLocked(r.level)
+	stopRevertLocked()
+	logOutput = r.output
+	timeFormat = r.timeFormat
+	staticFields = r.fields
+	redaction = r.redaction
+	sampling = r.sampling
+	removeSinksLocked(configSinks...)
+	configSinks = nil
+	for _, s := range opened {
+		configSinks = append(configSinks, addSinkLocked(s))
+	}
+	closing := configFiles
+	configFiles = files
+	configMutex.Unlock()
+
+	resetSampleCounts()
+	for _, f := range closing {
+		f.Close()
+	}
+	auditLevelChange(previous, r.level, "config", 0)
+	return nil
+}
+
+// WatchConfig loads the file and then polls it every interval, applying it again
+// whenever its modification time or size changes. Invalid reloads are logged and
+// leave the previous setup in place. The returned function stops watching.
+// The interval must be positive.
+func WatchConfig(path string, interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("nabu: invalid config watch interval %v, expected a positive duration", interval)
+	}
+	if err := LoadConfig(path); err != nil {
+		return nil, err
+	}
+	last, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil || (info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+					continue
+				}
+				last = info
+				if err := LoadConfig(path); err != nil {
+					FromError(err).WithMessage("config reload failed").WithArgs(path).Log()
+					continue
+				}
+				FromMessage("config reloaded").WithArgs(path).Log()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }, nil
+}
+
+// resolvedConfig is a validated Config in its internal representation.
+type resolvedConfig struct {
+	level      levelState
+	output     LogOutput
+	sinks      []resolvedSink
+	sampling   Sampling
+	redaction  redactor
+	fields     map[string]any
+	timeFormat string
+}
+
+type resolvedSink struct {
+	key      string // "sinks.<name>", for errors when opening it
+	config   SinkConfig
+	encoder  Encoder
+	minLevel LogLevel
+}
+
+// resolve validates the Config and converts it into the internal representation.
+func (c Config) resolve() (resolvedConfig, error) {
+	r := resolvedConfig{level: levelState{level: LevelDebug}, output: OutputStderr, sampling: c.Sampling, timeFormat: TimeLayout}
+	if c.Level != "" {
+		l, err := ParseLevel(c.Level)
+		if err != nil {
+			return r, &ConfigError{Key: "level", Err: err}
+		}
+		r.level.level = l
+	}
+
+	for _, p := range sortedKeys(c.Rules) {
+		if strings.TrimSpace(p) == "" {
+			return r, &ConfigError{Key: "rules", Err: errors.New("empty pattern")}
+		}
+		l, err := ParseLevel(c.Rules[p])
+		if err != nil {
+			return r, &ConfigError{Key: "rules." + p, Err: err}
+		}
+		r.level.rules = append(r.level.rules, levelRule{pattern: p, level: l})
+	}
+
+	if c.Output != "" {
+		o, err := parseOutput(c.Output)
+		if err != nil {
+			return r, &ConfigError{Key: "output", Err: err}
+		}
+		r.output = o
+	}
+
+	for _, name := range sortedKeys(c.Sinks) {
+		s, err := resolveSink("sinks."+name, c.Sinks[name])
+		if err != nil {
+			return r, err
+		}
+		r.sinks = append(r.sinks, s)
+	}
+
+	switch {
+	case c.Sampling.Initial < 0:
+		return r, &ConfigError{Key: "sampling.initial", Err: errors.New("must not be negative")}
+	case c.Sampling.Thereafter < 0:
+		return r, &ConfigError{Key: "sampling.thereafter", Err: errors.New("must not be negative")}
+	case c.Sampling.Tick < 0:
+		return r, &ConfigError{Key: "sampling.tick", Err: errors.New("must not be negative")}
+	}
+
+	var rules Redaction
+	rules.Keys = c.Redaction.Keys
+	for _, name := range sortedKeys(c.Redaction.Patterns) {
+		re, err := regexp.Compile(c.Redaction.Patterns[name])
+		if err != nil {
+			return r, &ConfigError{Key: "redaction.patterns." + name, Err: err}
+		}
+		rules.Patterns = append(rules.Patterns, re)
+	}
+	r.redaction = newRedactor(rules)
+
+	r.fields = cloneFields(c.Fields)
+	if c.TimeFormat != "" {
+		if err := checkTimeFormat(c.TimeFormat); err != nil {
+			return r, &ConfigError{Key: "timeformat", Err: err}
+		}
+		r.timeFormat = c.TimeFormat
+	}
+	return r, nil
+}
+
+func resolveSink(key string, c SinkConfig) (resolvedSink, error) {
+	s := resolvedSink{key: key, config: c, minLevel: LevelDebug}
+	switch strings.ToLower(c.Type) {
+	case "", "file":
+		if c.Path == "" {
+			return s, &ConfigError{Key: key + ".path", Err: errors.New("file sinks need a path")}
+		}
+	case "stdout", "stderr":
+		if c.Rotation != (Rotation{}) {
+			return s, &ConfigError{Key: key + ".rotation", Err: errors.New("only file sinks are rotated")}
+		}
+	default:
+		return s, &ConfigError{Key: key + ".type", Err: fmt.Errorf("unknown sink type %q", c.Type)}
+	}
+
+	switch strings.ToLower(c.Encoder) {
+	case "", "json":
+		s.encoder = EncodeJSON
+	case "logfmt":
+		s.encoder = EncodeLogfmt
+	case "console":
+		s.encoder = FormatConsole
+	default:
+		return s, &ConfigError{Key: key + ".encoder", Err: fmt.Errorf("unknown encoder %q", c.Encoder)}
+	}
+
+	if c.Level != "" {
+		l, err := ParseLevel(c.Level)
+		if err != nil {
+			return s, &ConfigError{Key: key + ".level", Err: err}
+		}
+		s.minLevel = l
+	}
+
+	switch {
+	case c.Rotation.MaxSize < 0:
+		return s, &ConfigError{Key: key + ".rotation.maxsize", Err: errors.New("must not be negative")}
+	case c.Rotation.MaxBackups < 0:
+		return s, &ConfigError{Key: key + ".rotation.maxbackups", Err: errors.New("must not be negative")}
+	case c.Rotation.MaxAge < 0:
+		return s, &ConfigError{Key: key + ".rotation.maxage", Err: errors.New("must not be negative")}
+	}
+	return s, nil
+}
+
+// openSinks opens the sinks of the config, closing those already opened if one fails.
+func (r resolvedConfig) openSinks() ([]Sink, []*FileSink, error) {
+	var opened []Sink
+	var files []*FileSink
+	for _, s := range r.sinks {
+		var sink Sink
+		switch strings.ToLower(s.config.Type) {
+		case "stdout":
+			sink = NewWriterSink(os.Stdout, s.encoder)
+		case "stderr":
+			sink = NewWriterSink(os.Stderr, s.encoder)
+		default:
+			f, err := OpenFileSink(s.config.Path, s.encoder, s.config.Rotation)
+			if err != nil {
+				for _, f := range files {
+					f.Close()
+				}
+				return nil, nil, &ConfigError{Key: s.key + ".path", Err: err}
+			}
+			files = append(files, f)
+			sink = f
+		}
+		if s.minLevel > LevelDebug {
+			sink = LevelSink(s.minLevel, sink)
+		}
+		opened = append(opened, sink)
+	}
+	return opened, files, nil
+}
+
+// checkTimeFormat rejects layouts that would write the same date at any time.
+func checkTimeFormat(layout string) error {
+	if time.Unix(0, 0).UTC().Format(layout) == time.Unix(1e9+86400+1, 0).UTC().Format(layout) {
+		return fmt.Errorf("layout %q does not format the date", layout)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// configFromMap converts a decoded JSON or YAML document into a Config.
+// Keys are matched case-insensitively.
+func configFromMap(m map[string]any) (Config, error) {
+	var c Config
+	for _, k := range sortedKeys(m) {
+		v := m[k]
+		var err error
+		switch key := strings.ToLower(k); key {
+		case "level":
+			c.Level, err = stringValue(key, v)
+		case "output":
+			c.Output, err = stringValue(key, v)
+		case "timeformat":
+			c.TimeFormat, err = stringValue(key, v)
+		case "rules":
+			c.Rules, err = rulesFromValue(key, v)
+		case "fields":
+			c.Fields, err = mapValue(key, v)
+		case "sinks":
+			c.Sinks, err = sinksFromValue(key, v)
+		case "sampling":
+			c.Sampling, err = samplingFromValue(key, v)
+		case "redaction":
+			c.Redaction, err = redactionFromValue(key, v)
+		default:
+			err = &ConfigError{Key: k, Err: ErrUnknownConfigKey}
+		}
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	if _, err := c.resolve(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// fieldsOf calls fn with each key of the mapping v, reporting unknown keys for fn returning false.
+func fieldsOf(key string, v any, fn func(k string, v any) (bool, error)) error {
+	m, err := mapValue(key, v)
+	if err != nil {
+		return err
+	}
+	for _, k := range sortedKeys(m) {
+		known, err := fn(strings.ToLower(k), m[k])
+		if err != nil {
+			return err
+		}
+		if !known {
+			return &ConfigError{Key: key + "." + k, Err: ErrUnknownConfigKey}
+		}
+	}
+	return nil
+}
+
+func sinksFromValue(key string, v any) (map[string]SinkConfig, error) {
+	m, err := mapValue(key, v)
+	if err != nil {
+		return nil, err
+	}
+	sinks := make(map[string]SinkConfig, len(m))
+	for _, name := range sortedKeys(m) {
+		var s SinkConfig
+		prefix := key + "." + name
+		err := fieldsOf(prefix, m[name], func(k string, v any) (bool, error) {
+			var err error
+			switch k {
+			case "type":
+				s.Type, err = stringValue(prefix+".type", v)
+			case "path":
+				s.Path, err = stringValue(prefix+".path", v)
+			case "encoder":
+				s.Encoder, err = stringValue(prefix+".encoder", v)
+			case "level":
+				s.Level, err = stringValue(prefix+".level", v)
+			case "rotation":
+				s.Rotation, err = rotationFromValue(prefix+".rotation", v)
+			default:
+				return false, nil
+			}
+			return true, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		sinks[name] = s
+	}
+	return sinks, nil
+}
+
+func rotationFromValue(key string, v any) (Rotation, error) {
+	var r Rotation
+	err := fieldsOf(key, v, func(k string, v any) (bool, error) {
+		var err error
+		switch k {
+		case "maxsize":
+			r.MaxSize, err = sizeValue(key+".maxsize", v)
+		case "maxbackups":
+			r.MaxBackups, err = intValue(key+".maxbackups", v)
+		case "maxage":
+			r.MaxAge, err = durationValue(key+".maxage", v)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return r, err
+}
+
+func samplingFromValue(key string, v any) (Sampling, error) {
+	var s Sampling
+	err := fieldsOf(key, v, func(k string, v any) (bool, error) {
+		var err error
+		switch k {
+		case "initial":
+			s.Initial, err = intValue(key+".initial", v)
+		case "thereafter":
+			s.Thereafter, err = intValue(key+".thereafter", v)
+		case "tick":
+			s.Tick, err = durationValue(key+".tick", v)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return s, err
+}
+
+func redactionFromValue(key string, v any) (RedactionConfig, error) {
+	var r RedactionConfig
+	err := fieldsOf(key, v, func(k string, v any) (bool, error) {
+		switch k {
+		case "keys":
+			keys, err := stringsValue(key+".keys", v)
+			r.Keys = keys
+			return true, err
+		case "patterns":
+			m, err := mapValue(key+".patterns", v)
+			if err != nil {
+				return true, err
+			}
+			r.Patterns = make(map[string]string, len(m))
+			for _, name := range sortedKeys(m) {
+				if r.Patterns[name], err = stringValue(key+".patterns."+name, m[name]); err != nil {
+					return true, err
+				}
+			}
+			return true, nil
+		}
+		return false, nil
+	})
+	return r, err
+}
+
+func stringValue(key string, v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", &ConfigError{Key: key, Err: errors.New("expected a string")}
+	}
+	return s, nil
+}
+
+func mapValue(key string, v any) (map[string]any, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, &ConfigError{Key: key, Err: errors.New("expected a mapping")}
+	}
+	return m, nil
+}
+
+// stringsValue accepts a list of strings, or a comma-separated string since YAML files have no lists.
+func stringsValue(key string, v any) ([]string, error) {
+	switch x := v.(type) {
+	case string:
+		var list []string
+		for _, s := range strings.Split(x, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		return list, nil
+	case []any:
+		list := make([]string, len(x))
+		for i, e := range x {
+			s, ok := e.(string)
+			if !ok {
+				return nil, &ConfigError{Key: key, Err: errors.New("expected a list of strings")}
+			}
+			list[i] = s
+		}
+		return list, nil
+	}
+	return nil, &ConfigError{Key: key, Err: errors.New("expected a list of strings")}
+}
+
+// intValue accepts JSON numbers and YAML scalars.
+func intValue(key string, v any) (int, error) {
+	switch x := v.(type) {
+	case float64:
+		if x == float64(int(x)) {
+			return int(x), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(x)); err == nil {
+			return n, nil
+		}
+	}
+	return 0, &ConfigError{Key: key, Err: fmt.Errorf("expected an integer, got %v", v)}
+}
+
+func durationValue(key string, v any) (time.Duration, error) {
+	s, err := stringValue(key, v)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, &ConfigError{Key: key, Err: err}
+	}
+	return d, nil
+}
+
+// sizeUnits are the suffixes accepted by sizeValue, longest first.
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+// sizeValue accepts a number of bytes, or a number followed by B, KB, MB or GB.
+func sizeValue(key string, v any) (int64, error) {
+	s, ok := v.(string)
+	if !ok {
+		n, err := intValue(key, v)
+		return int64(n), err
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if rest, found := strings.CutSuffix(s, u.suffix); found {
+			s, unit = strings.TrimSpace(rest), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, &ConfigError{Key: key, Err: fmt.Errorf("expected a size such as 10MB, got %v", v)}
+	}
+	return n * unit, nil
+}
+
+// rulesFromValue accepts rules either as a mapping or as a SetLevelRules spec string.
+func rulesFromValue(key string, v any) (map[string]string, error) {
+	switch r := v.(type) {
+	case string:
+		global, rules, err := parseLevelSpec(r)
+		if err != nil {
+			return nil, &ConfigError{Key: key, Err: err}
+		}
+		if global != nil {
+			return nil, &ConfigError{Key: key, Err: errors.New("entries must be of the form pattern=level")}
+		}
+		m := make(map[string]string, len(rules))
+		for _, rule := range rules {
+			m[rule.pattern] = rule.level.String()
+		}
+		return m, nil
+	case map[string]any:
+		m := make(map[string]string, len(r))
+		for p, l := range r {
+			s, ok := l.(string)
+			if !ok {
+				return nil, &ConfigError{Key: key + "." + p, Err: errors.New("expected a level name")}
+			}
+			m[p] = s
+		}
+		return m, nil
+	default:
+		return nil, &ConfigError{Key: key, Err: errors.New("expected a mapping or a string")}
+	}
+}
+
+// parseOutput converts an output name into a LogOutput.
+func parseOutput(s string) (LogOutput, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "stderr":
+		return OutputStderr, nil
+	case "stdout":
+		return OutputStdout, nil
+	case "internal":
+		return OutputInternal, nil
+	case "none":
+		return OutputNone, nil
+	}
+	return OutputStderr, fmt.Errorf("unknown output %q", s)
+}
+
+// parseYAML parses the YAML subset used by configuration files:
+// nested mappings indented with spaces, plain or quoted scalars and # comments.
+func parseYAML(data []byte) (map[string]any, error) {
+	type frame struct {
+		indent int
+		m      map[string]any
+	}
+	root := make(map[string]any)
+	stack := []frame{{indent: -1, m: root}}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.HasPrefix(line[indent:], "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			return nil, fmt.Errorf("line %d: sequences are not supported", lineNo)
+		}
+
+		key, value, err := splitYAMLEntry(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		for stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].m
+		if _, exists := parent[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+		}
+
+		if value == "" {
+			child := make(map[string]any)
+			parent[key] = child
+			stack = append(stack, frame{indent: indent, m: child})
+			continue
+		}
+		parent[key] = value
+	}
+	return root, nil
+}
+
+// splitYAMLEntry splits "key: value # comment" into an unquoted key and value.
+func splitYAMLEntry(s string) (string, string, error) {
+	key, rest, err := readYAMLScalar(s, true)
+	if err != nil {
+		return "", "", err
+	}
+	rest = strings.TrimLeft(rest, " ")
+	if !strings.HasPrefix(rest, ":") {
+		return "", "", fmt.Errorf("expected ':' after key %q", key)
+	}
+	rest = strings.TrimSpace(rest[1:])
+	if rest == "" || strings.HasPrefix(rest, "#") {
+		return key, "", nil
+	}
+	value, rest, err := readYAMLScalar(rest, false)
+	if err != nil {
+		return "", "", err
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", "", fmt.Errorf("unexpected content after value: %q", rest)
+	}
+	return key, value, nil
+}
+
+// readYAMLScalar reads a plain or quoted scalar and returns it along with the remaining text.
+// Plain keys end at ": " or a trailing ':'; plain values end at " #".
+func readYAMLScalar(s string, isKey bool) (string, string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				v, err := strconv.Unquote(s[:i+1])
+				return v, s[i+1:], err
+			}
+		}
+		return "", "", errors.New("unterminated double-quoted string")
+	case strings.HasPrefix(s, "'"):
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", "", errors.New("unterminated single-quoted string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case isKey:
+		if i := strings.Index(s, ": "); i >= 0 {
+			return strings.TrimSpace(s[:i]), s[i:], nil
+		}
+		if strings.HasSuffix(s, ":") {
+			return strings.TrimSpace(s[:len(s)-1]), ":", nil
+		}
+		return "", "", errors.New("expected 'key: value'")
+	default:
+		if i := strings.Index(s, " #"); i >= 0 {
+			return strings.TrimSpace(s[:i]), s[i:], nil
+		}
+		return strings.TrimSpace(s), "", nil
+	}
+}
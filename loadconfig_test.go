@@ -0,0 +1,367 @@
+package nabu
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadConfigJSON(t *testing.T) {
+	path := writeConfigFile(t, "nabu.json", `{"Level":"warn","Output":"stdout","Rules":{"github.com/acme/db/*":"debug"}}`)
+
+	c, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Level != "warn" || c.Output != "stdout" || c.Rules["github.com/acme/db/*"] != "debug" {
+		t.Errorf("Unexpected config: %+v", c)
+	}
+}
+
+func TestReadConfigYAML(t *testing.T) {
+	path := writeConfigFile(t, "nabu.yaml", `# nabu configuration
+level: info   # global level
+output: "internal"
+rules:
+  github.com/acme/db/*: debug
+  'github.com/acme/http': warn
+`)
+
+	c, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Level != "info" || c.Output != "internal" {
+		t.Errorf("Unexpected config: %+v", c)
+	}
+	if c.Rules["github.com/acme/db/*"] != "debug" || c.Rules["github.com/acme/http"] != "warn" || len(c.Rules) != 2 {
+		t.Errorf("Unexpected rules: %v", c.Rules)
+	}
+}
+
+func TestReadConfigRulesSpec(t *testing.T) {
+	path := writeConfigFile(t, "nabu.yml", "rules: github.com/acme/db/*=debug,github.com/acme/http=warn\n")
+
+	c, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(c.Rules) != 2 || c.Rules["github.com/acme/http"] != "warn" {
+		t.Errorf("Unexpected rules: %v", c.Rules)
+	}
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		key     string
+		err     error
+	}{
+		{"level.json", `{"Level":"loud"}`, "level", nil},
+		{"rules.json", `{"Rules":{"github.com/acme/db":"loud"}}`, "rules.github.com/acme/db", nil},
+		{"output.yaml", "output: syslog\n", "output", nil},
+		{"unknown.yaml", "colour: always\n", "colour", ErrUnknownConfigKey},
+		{"sink.json", `{"Sinks":{"audit":{"Path":"audit.log","Colour":"red"}}}`, "sinks.audit.Colour", ErrUnknownConfigKey},
+		{"encoder.yaml", "sinks:\n  audit:\n    path: audit.log\n    encoder: xml\n", "sinks.audit.encoder", nil},
+		{"path.yaml", "sinks:\n  audit:\n    type: file\n", "sinks.audit.path", nil},
+		{"type.yaml", "sinks:\n  audit:\n    type: syslog\n", "sinks.audit.type", nil},
+		{"size.yaml", "sinks:\n  audit:\n    path: a.log\n    rotation:\n      maxsize: huge\n", "sinks.audit.rotation.maxsize", nil},
+		{"sampling.json", `{"Sampling":{"Initial":-1}}`, "sampling.initial", nil},
+		{"tick.yaml", "sampling:\n  tick: often\n", "sampling.tick", nil},
+		{"pattern.yaml", "redaction:\n  patterns:\n    card: '[0-9'\n", "redaction.patterns.card", nil},
+		{"time.yaml", "timeformat: banana\n", "timeformat", nil},
+		{"fields.yaml", "fields: service=api\n", "fields", nil},
+	}
+	for _, c := range cases {
+		_, err := ReadConfig(writeConfigFile(t, c.name, c.content))
+		var ce *ConfigError
+		if !errors.As(err, &ce) {
+			t.Errorf("%s: expected a ConfigError, got %v", c.name, err)
+			continue
+		}
+		if ce.Key != c.key {
+			t.Errorf("%s: expected key %q, got %q", c.name, c.key, ce.Key)
+		}
+		if c.err != nil && !errors.Is(err, c.err) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+		if strings.Count(err.Error(), "nabu:") != 1 {
+			t.Errorf("%s: expected a single nabu: prefix, got %q", c.name, err)
+		}
+	}
+}
+
+func TestReadConfigComplete(t *testing.T) {
+	path := writeConfigFile(t, "nabu.yaml", `level: info
+output: none
+timeformat: 2006-01-02T15:04:05.000000Z07:00
+fields:
+  service: api
+sampling:
+  initial: 10
+  thereafter: 100
+  tick: 1s
+redaction:
+  keys: password, token
+  patterns:
+    card: '\d{16}'
+sinks:
+  audit:
+    path: /var/log/app/audit.log
+    encoder: logfmt
+    level: warn
+    rotation:
+      maxsize: 10MB
+      maxbackups: 5
+      maxage: 168h
+  console:
+    type: stderr
+    encoder: console
+`)
+
+	c, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Output != "none" || c.TimeFormat != "2006-01-02T15:04:05.000000Z07:00" || c.Fields["service"] != "api" {
+		t.Errorf("Unexpected config: %+v", c)
+	}
+	if c.Sampling != (Sampling{Initial: 10, Thereafter: 100, Tick: time.Second}) {
+		t.Errorf("Unexpected sampling: %+v", c.Sampling)
+	}
+	if !reflect.DeepEqual(c.Redaction, RedactionConfig{Keys: []string{"password", "token"}, Patterns: map[string]string{"card": `\d{16}`}}) {
+		t.Errorf("Unexpected redaction: %+v", c.Redaction)
+	}
+	want := map[string]SinkConfig{
+		"audit":   {Path: "/var/log/app/audit.log", Encoder: "logfmt", Level: "warn", Rotation: Rotation{MaxSize: 10 << 20, MaxBackups: 5, MaxAge: 168 * time.Hour}},
+		"console": {Type: "stderr", Encoder: "console"},
+	}
+	if !reflect.DeepEqual(c.Sinks, want) {
+		t.Errorf("Unexpected sinks: %+v", c.Sinks)
+	}
+
+	// JSON lists are accepted as well as comma-separated strings
+	c, err = ReadConfig(writeConfigFile(t, "nabu.json", `{"Redaction":{"Keys":["password"]},"Sinks":{"a":{"Path":"a.log","Rotation":{"MaxSize":1024}}}}`))
+	if err != nil || len(c.Redaction.Keys) != 1 || c.Sinks["a"].Rotation.MaxSize != 1024 {
+		t.Errorf("Unexpected config: %+v, %v", c, err)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	invalid := []string{
+		"level:\n\t info: x\n",
+		"rules:\n  - a\n",
+		"level info\n",
+		"level: info\nlevel: warn\n",
+		"level: \"info\n",
+		"level: 'info' trailing\n",
+	}
+	for _, content := range invalid {
+		if _, err := parseYAML([]byte(content)); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvLevel, "warn,github.com/acme/db/*=debug")
+	t.Setenv(EnvOutput, "stdout")
+
+	c, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Level != "warn" || c.Output != "stdout" || c.Rules["github.com/acme/db/*"] != "debug" {
+		t.Errorf("Unexpected config: %+v", c)
+	}
+
+	t.Setenv(EnvTimeFormat, time.RFC3339)
+	t.Setenv(EnvFields, "service=api, env=prod")
+	c, err = ConfigFromEnv()
+	if err != nil || c.TimeFormat != time.RFC3339 || c.Fields["service"] != "api" || c.Fields["env"] != "prod" {
+		t.Errorf("Unexpected config: %+v, %v", c, err)
+	}
+
+	t.Setenv(EnvFields, "service")
+	if _, err = ConfigFromEnv(); err == nil || !strings.Contains(err.Error(), EnvFields) {
+		t.Errorf("Expected ConfigError for %s, got %v", EnvFields, err)
+	}
+	t.Setenv(EnvFields, "")
+
+	t.Setenv(EnvOutput, "syslog")
+	_, err = ConfigFromEnv()
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Key != EnvOutput {
+		t.Errorf("Expected ConfigError for %s, got %v", EnvOutput, err)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	defer ClearLevelRules()
+	defer SetLogOutput(OutputInternal)
+
+	err := ApplyConfig(Config{Level: "error", Rules: map[string]string{"github.com/acme/db/*": "debug"}, Output: "internal"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if GetLogLevel() != LevelError || GetLevelRules() != "github.com/acme/db/*=debug" {
+		t.Errorf("Unexpected state: level=%v rules=%q", GetLogLevel(), GetLevelRules())
+	}
+
+	// An empty config restores the defaults
+	if err := ApplyConfig(Config{Output: "internal"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if GetLogLevel() != LevelDebug || GetLevelRules() != "" {
+		t.Errorf("Expected defaults, got level=%v rules=%q", GetLogLevel(), GetLevelRules())
+	}
+
+	// Invalid configs are not applied at all
+	if err := ApplyConfig(Config{Level: "warn", Output: "syslog"}); err == nil {
+		t.Error("Expected error for invalid output")
+	}
+	if GetLogLevel() != LevelDebug {
+		t.Errorf("Expected level to remain unchanged, got %v", GetLogLevel())
+	}
+}
+
+func TestApplyConfigCancelsRevert(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	defer SetLogOutput(OutputInternal)
+
+	SetLogLevelFor(LevelDebug, 20*time.Millisecond)
+	resetTestState()
+	if err := ApplyConfig(Config{Level: "warn", Output: "internal"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(getInternalOutput(), `"Source","config"`) {
+		t.Errorf("Expected an audit entry for the loaded level, got: %s", getInternalOutput())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if GetLogLevel() != LevelWarn {
+		t.Errorf("Expected the loaded level to survive the pending revert, got %v", GetLogLevel())
+	}
+}
+
+func TestApplyConfigSinks(t *testing.T) {
+	defer SetLogOutput(OutputInternal)
+	defer ApplyConfig(Config{Output: "internal"})
+
+	dir := t.TempDir()
+	err := ApplyConfig(Config{
+		Output:     "internal",
+		TimeFormat: "2006-01-02T15:04:05Z07:00",
+		Fields:     map[string]any{"service": "api"},
+		Redaction:  RedactionConfig{Keys: []string{"password"}, Patterns: map[string]string{"card": `\d{16}`}},
+		Sinks: map[string]SinkConfig{
+			"all":    {Path: filepath.Join(dir, "all.log"), Encoder: "logfmt"},
+			"errors": {Path: filepath.Join(dir, "errors.log"), Level: "error"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resetTestState()
+	FromMessage("paid with 4111111111111111").WithArgs("password", "hunter2").Log()
+	FromError(errors.New("declined")).Log()
+
+	all, _ := os.ReadFile(filepath.Join(dir, "all.log"))
+	lines := strings.Split(strings.TrimSpace(string(all)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `msg="paid with [REDACTED]" args="[\"password\",\"[REDACTED]\"]"`) || !strings.HasSuffix(lines[0], "service=api") {
+		t.Errorf("Unexpected logfmt sink: %s", all)
+	}
+	if !strings.HasPrefix(lines[0], "date="+time.Now().UTC().Format("2006-01-02T")) {
+		t.Errorf("Expected dates in the configured layout, got %s", lines[0])
+	}
+	errs, _ := os.ReadFile(filepath.Join(dir, "errors.log"))
+	if entry := fromJson(string(errs)); entry == nil || entry.Error != "declined" || entry.Fields["service"] != "api" {
+		t.Errorf("Expected only the error in the JSON sink, got %s", errs)
+	}
+	if strings.Contains(getInternalOutput(), "hunter2") {
+		t.Errorf("Expected the output to be redacted too, got %s", getInternalOutput())
+	}
+
+	// Reloading replaces the sinks of the previous config
+	if err := ApplyConfig(Config{Output: "internal"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resetTestState()
+	FromMessage("after reload").Log()
+	if all, _ := os.ReadFile(filepath.Join(dir, "all.log")); strings.Contains(string(all), "after reload") {
+		t.Errorf("Expected the sink to be removed, got %s", all)
+	}
+	if strings.Contains(getInternalOutput(), `"Fields"`) {
+		t.Errorf("Expected the fields to be removed, got %s", getInternalOutput())
+	}
+
+	// A sink that cannot be opened leaves the setup unchanged
+	err = ApplyConfig(Config{Level: "error", Sinks: map[string]SinkConfig{"bad": {Path: filepath.Join(dir, "missing", "a.log")}}})
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Key != "sinks.bad.path" || GetLogLevel() == LevelError {
+		t.Errorf("Expected the config to be rejected, got %v", err)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	defer ClearLevelRules()
+	defer SetLogOutput(OutputInternal)
+
+	path := writeConfigFile(t, "nabu.yaml", "level: warn\noutput: internal\n")
+	stop, err := WatchConfig(path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer stop()
+
+	if GetLogLevel() != LevelWarn {
+		t.Fatalf("Expected initial level Warn, got %v", GetLogLevel())
+	}
+
+	// Ensure the modification time differs on filesystems with coarse timestamps
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("level: error\noutput: internal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for GetLogLevel() != LevelError && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if GetLogLevel() != LevelError {
+		t.Errorf("Expected reloaded level Error, got %v", GetLogLevel())
+	}
+}
+
+func TestWatchConfigInvalid(t *testing.T) {
+	path := writeConfigFile(t, "nabu.yaml", "level: loud\n")
+	if _, err := WatchConfig(path, time.Second); err == nil || !strings.Contains(err.Error(), `"level"`) {
+		t.Errorf("Expected error mentioning the level key, got %v", err)
+	}
+
+	path = writeConfigFile(t, "nabu.yaml", "level: info\n")
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := WatchConfig(path, interval); err == nil || !strings.Contains(err.Error(), "interval") {
+			t.Errorf("%v: expected an invalid interval, got %v", interval, err)
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
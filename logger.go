@@ -1,10 +1,8 @@
 package nabu
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"os"
-	"strings"
 
 	"github.com/google/uuid"
 )
@@ -16,8 +14,11 @@ func New() *Logger {
 
 // FromError creates a Logger instance from an error.
 // If the error is nil, an empty Logger is returned.
-// If the error is a *Logger, its UUID is preserved to maintain the error chain.
+// If the error is a *Logger, its UUID is preserved to maintain the error chain,
+// along with its code, category, retryable flag and parent UUID.
 // If the wrapped Logger has no UUID, a new one is generated for the chain.
+// If the error joins several chains, a new UUID is generated and the joined
+// UUIDs are kept in Related.
 // Otherwise, a new UUID is generated for tracking related logs.
 func FromError(e error) *Logger {
 	x := New()
@@ -30,16 +31,24 @@ func FromError(e error) *Logger {
 	x.CausedBy = e
 	x.enableStackTrace = true
 
-	var ex *Logger
-	if errors.As(e, &ex) {
-		// Preserve UUID from the wrapped Logger, or generate one if it doesn't have one
-		if ex.UUID != "" {
-			x.UUID = ex.UUID
-		} else {
-			x.UUID = uuid.NewString()
-		}
-	} else {
+	// Preserve UUID from the wrapped Logger, or generate one if it doesn't have one.
+	// When several chains are joined (e.g. errors.Join), a new chain is started
+	// that records the UUIDs of the chains it joins.
+	inner := innerLogger(e)
+	x.inheritClassification(inner)
+	if inner != nil {
+		x.ParentUUID = inner.ParentUUID
+	}
+
+	uuids := chainUuids(e)
+	switch len(uuids) {
+	case 0:
 		x.UUID = uuid.NewString()
+	case 1:
+		x.UUID = uuids[0]
+	default:
+		x.UUID = uuid.NewString()
+		x.Related = uuids
 	}
 
 	return x
@@ -77,6 +86,23 @@ func (x *Logger) WithUuid(uuid string) *Logger {
 	return x
 }
 
+// WithParentUuid records the UUID of the chain in whose context this chain
+// started, e.g. a request that spawned a background job, so that Parser can
+// rebuild the tree of chains (see ParsedLogs.TraceTree).
+func (x *Logger) WithParentUuid(uuid string) *Logger {
+	x.ParentUUID = uuid
+	return x
+}
+
+// WithContext sets the parent UUID from the UUID stored in ctx by ContextWithUuid,
+// unless it is the UUID of this chain.
+func (x *Logger) WithContext(ctx context.Context) *Logger {
+	if uuid := UuidFromContext(ctx); uuid != "" && uuid != x.UUID {
+		x.ParentUUID = uuid
+	}
+	return x
+}
+
 // WithLevelDebug sets the log level to Debug.
 func (x *Logger) WithLevelDebug() *Logger {
 	x.Level = LevelDebug
@@ -116,7 +142,12 @@ func (x *Logger) EnableStackTrace() *Logger {
 
 // Error implements the error interface to allow using Logger as an error.
 // Returns the underlying error message or empty string if no error is present.
+// When SetErrorMessageChain is enabled, the messages added along the chain are
+// prepended, e.g. "operation failed: query failed: EOF".
 func (x *Logger) Error() string {
+	if errorMessageChainEnabled() {
+		return x.chainMessage()
+	}
 	if x.CausedBy != nil {
 		return x.CausedBy.Error()
 	}
@@ -130,31 +161,69 @@ func (x *Logger) Unwrap() error {
 }
 
 // Log outputs the log entry and returns itself as an error.
-// This method checks if the log level is enabled before writing the log.
+// This method checks if the log level is enabled, and that SetSampling does not drop the entry, before writing the log.
 // If the log originates from an error but no error is set, nothing is logged.
 // The log entry includes timestamp, UUID, message/error, arguments and stack trace if enabled.
 // The output is directed to the configured output destination (stderr by default).
+// The date and stack trace are recorded on the Logger even when the level is
+// filtered out, so that ErrorChain and %+v formatting remain complete.
+// When SetDeferredChains is enabled, error Loggers only record their frame,
+// unless they are marked with Boundary, in which case the whole chain is written.
 func (x *Logger) Log() error {
-	if !shouldLog(x.Level) {
+	if x.origin == originError && x.CausedBy == nil {
 		return x
 	}
 
-	if x.origin == originError && x.CausedBy == nil {
+	x.date, x.seq = getDate(), nextSeq()
+	if x.enableStackTrace {
+		x.function, x.line = x.getFirstTrace()
+	}
+
+	if x.origin == originError && !x.boundary && deferredChainsEnabled() {
 		return x
 	}
 
+	if !x.audit && (!shouldLogCaller(x.Level) || !sampled(x.Level, x.Msg)) {
+		return x
+	}
+
+	if x.boundary && deferredChainsEnabled() {
+		writeLog(x.chainOutput())
+	} else {
+		writeLog(x.output())
+	}
+
+	return x
+}
+
+// output converts the Logger into the entry written by Log.
+func (x *Logger) output() Output {
 	o := Output{
-		UUID:  x.UUID,
-		Date:  getDate(),
-		Args:  x.Args,
-		Msg:   x.Msg,
-		Level: x.Level,
+		UUID:       x.UUID,
+		Date:       x.date,
+		Seq:        x.seq,
+		ParentUUID: x.ParentUUID,
+		Args:       x.Args,
+		Msg:        x.Msg,
+		Function:   x.function,
+		Line:       x.line,
+		Level:      x.Level,
+		Code:       x.code,
+		Category:   x.category,
+		Retryable:  x.retryable,
+		Stack:      x.stack,
 	}
 	if x.CausedBy != nil {
 		// Only show the immediate error, not the full chain
 		// This prevents error duplication across the stack
 		var loggerErr *Logger
-		if errors.As(x.CausedBy, &loggerErr) {
+		if joined := joinedErrors(x.CausedBy); len(joined) > 1 {
+			// Several errors are joined - record each of them
+			for _, e := range joined {
+				o.Errors = append(o.Errors, e.Error())
+			}
+			o.Related = x.Related
+		} else if errors.As(x.CausedBy, &loggerErr) {
 			// CausedBy is a Logger - don't show its error in this log
 			// The error was already logged when that Logger was created
 		} else {
@@ -162,20 +231,5 @@ func (x *Logger) Log() error {
 			o.Error = x.CausedBy.Error()
 		}
 	}
-	if x.enableStackTrace {
-		o.Function, o.Line = x.getFirstTrace()
-	}
-
-	log := toJson(o)
-
-	switch logOutput {
-	case OutputInternal:
-		internalOutput += strings.TrimSpace(log) + "\n"
-	case OutputStdout:
-		fmt.Fprintln(os.Stdout, log)
-	case OutputStderr:
-		fmt.Fprintln(os.Stderr, log)
-	}
-
-	return x
+	return o
 }
@@ -351,6 +351,66 @@ func TestMixedStack(t *testing.T) {
 	})
 }
 
+func TestFromErrorJoined(t *testing.T) {
+	resetTestState()
+
+	errA := FromError(errors.New("worker A failed")).WithMessage("A").Log()
+	errB := FromError(errors.New("worker B failed")).WithMessage("B").Log()
+	joined := FromError(errors.Join(errA, errB, errors.New("plain"))).WithMessage("workers failed").Log()
+
+	lines := strings.Split(strings.TrimSpace(getInternalOutput()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 log entries, got %d", len(lines))
+	}
+	entryA, entryB, entryJoined := fromJson(lines[0]), fromJson(lines[1]), fromJson(lines[2])
+
+	if entryJoined.UUID == entryA.UUID || entryJoined.UUID == entryB.UUID {
+		t.Error("Expected the join to start a new chain")
+	}
+	expectedRelated := []string{entryA.UUID, entryB.UUID}
+	if !reflect.DeepEqual(entryJoined.Related, expectedRelated) {
+		t.Errorf("Expected Related %v, got %v", expectedRelated, entryJoined.Related)
+	}
+	expectedErrors := []string{"worker A failed", "worker B failed", "plain"}
+	if !reflect.DeepEqual(entryJoined.Errors, expectedErrors) {
+		t.Errorf("Expected Errors %v, got %v", expectedErrors, entryJoined.Errors)
+	}
+	if entryJoined.Error != "" {
+		t.Errorf("Expected no Error for joined errors, got %q", entryJoined.Error)
+	}
+
+	// Wrapping the joined chain keeps its UUID
+	resetTestState()
+	FromError(joined).WithMessage("request failed").Log()
+	outer := fromJson(getInternalOutput())
+	if outer.UUID != entryJoined.UUID {
+		t.Errorf("Expected outer UUID %s, got %s", entryJoined.UUID, outer.UUID)
+	}
+	if len(outer.Related) != 0 || len(outer.Errors) != 0 {
+		t.Errorf("Expected outer entry not to repeat the join, got %+v", outer)
+	}
+
+	if !errors.Is(joined, errA) || !errors.Is(joined, errB) {
+		t.Error("Expected errors.Is to reach the joined chains")
+	}
+}
+
+func TestFromErrorJoinedSingleChain(t *testing.T) {
+	resetTestState()
+
+	inner := FromError(errors.New("only chain")).Log()
+	FromError(errors.Join(inner, nil)).Log()
+
+	lines := strings.Split(strings.TrimSpace(getInternalOutput()), "\n")
+	first, second := fromJson(lines[0]), fromJson(lines[1])
+	if first.UUID != second.UUID {
+		t.Error("Expected a join of a single chain to preserve its UUID")
+	}
+	if len(second.Related) != 0 {
+		t.Errorf("Expected no Related UUIDs, got %v", second.Related)
+	}
+}
+
 var (
 	ErrSentinel = errors.New("sentinel error")
 )
@@ -437,5 +497,3 @@ func BenchmarkFromErrorSimpleNil(b *testing.B) {
 		resetTestState()
 	}
 }
-
-
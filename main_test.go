@@ -6,8 +6,8 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	SetLogLevel(LevelDebug)
 	SetLogOutput(OutputInternal)
+	SetLogLevel(LevelDebug)
 
 	code := m.Run()
 	os.Exit(code)
@@ -30,6 +30,8 @@ const (
 	OutputStdout
 	// OutputInternal stores logs in an internal buffer for testing
 	OutputInternal
+	// OutputNone writes entries to the sinks only, see AddSink
+	OutputNone
 )
 
 const (
@@ -43,40 +45,102 @@ const TimeLayout = "2006-01-02 15:04:05.000000"
 
 // Output represents the JSON structure of a log entry.
 type Output struct {
-	UUID     string   `json:",omitempty"` // Unique identifier for tracking related log entries
-	Date     string   `json:",omitempty"` // Timestamp when log was created
-	Error    string   `json:",omitempty"` // Error message if this is an error log
-	Args     any      `json:",omitempty"` // Additional structured data for the log entry
-	Msg      string   `json:",omitempty"` // Main log message
-	Function string   `json:",omitempty"` // Function where the log was generated
-	Line     int      `json:",omitempty"` // Line number where the log was generated
-	Level    LogLevel `json:",omitempty"` // Severity level of the log
+	UUID       string         `json:",omitempty"` // Unique identifier for tracking related log entries
+	Date       string         `json:",omitempty"` // Timestamp when log was created
+	Seq        uint64         `json:",omitempty"` // Order in which entries were created in the process
+	Error      string         `json:",omitempty"` // Error message if this is an error log
+	Errors     []string       `json:",omitempty"` // Each constituent error when several errors are joined
+	Related    []string       `json:",omitempty"` // UUIDs of the chains joined into this one
+	ParentUUID string         `json:",omitempty"` // UUID of the chain in whose context this chain started
+	Args       any            `json:",omitempty"` // Additional structured data for the log entry
+	Msg        string         `json:",omitempty"` // Main log message
+	Function   string         `json:",omitempty"` // Function where the log was generated
+	Line       int            `json:",omitempty"` // Line number where the log was generated
+	Level      LogLevel       `json:",omitempty"` // Severity level of the log
+	Code       string         `json:",omitempty"` // Application-specific error code
+	Category   Category       `json:",omitempty"` // Classification of the error
+	Retryable  bool           `json:",omitempty"` // Whether the operation can be retried
+	Stack      string         `json:",omitempty"` // Full goroutine stack, for recovered panics
+	Fields     map[string]any `json:",omitempty"` // Static fields added to every entry, see SetFields
+	Source     string         `json:",omitempty"` // Parser source the entry was read from, see Parser.AddSource
+	Chain      []Output       `json:",omitempty"` // Every frame of the chain, oldest first, when written by a boundary
 }
 
 // Logger is the main logging object that holds log details before they're written.
 type Logger struct {
-	CausedBy error    // Original error that caused this log entry
-	UUID     string   // Unique identifier for related log entries
-	Msg      string   // Log message
-	Args     any      // Additional structured data
-	Level    LogLevel // Severity level
+	CausedBy   error    // Original error that caused this log entry
+	UUID       string   // Unique identifier for related log entries
+	Related    []string // UUIDs of the chains joined by CausedBy
+	ParentUUID string   // UUID of the chain in whose context this chain started
+	Msg        string   // Log message
+	Args       any      // Additional structured data
+	Level      LogLevel // Severity level
 
 	origin           int  // Whether the log originated from an error or message
 	enableStackTrace bool // Whether to include stack trace information
+	audit            bool // Whether the entry bypasses the configured log level
+	boundary         bool // Whether Log writes the whole chain when chains are deferred
+
+	code      string   // Application-specific error code
+	category  Category // Classification of the error
+	retryable bool     // Whether the operation can be retried
+
+	stack    string // Full goroutine stack, for recovered panics
+	date     string // When Log was last called
+	seq      uint64 // Order in which Log was last called in the process
+	function string // Function where Log was called, if stack trace is enabled
+	line     int    // Line where Log was called, if stack trace is enabled
 }
 
 type ParsedErrorTrace struct {
-	UUID   string
-	Error  string
-	Frames []Output // Ordered oldest to newest
+	UUID       string
+	Error      string
+	Errors     []string // Constituent errors when the trace starts from joined errors
+	Related    []string // UUIDs of the traces joined into this one
+	JoinedBy   []string // UUIDs of the traces that joined this one
+	ParentUUID string   // UUID of the trace in whose context this trace started
+	Frames     []Output // Ordered oldest to newest
 }
 
 type ParsedLogs struct {
-	Entries []Output
-	Traces  []ParsedErrorTrace
+	Entries      []Output
+	Traces       []ParsedErrorTrace
+	Errors       []ParseError // Lines that were rejected, in input order
+	Unstructured []RawLine    // Lines that are not JSON, when KeepUnstructured is set
+	Checkpoint   *Checkpoint  // Where the next run resumes (see ResumeFrom), nil when sources are merged
+}
+
+// ParseError describes a line that could not be turned into an entry.
+type ParseError struct {
+	Source string // File the line was read from, if any
+	Line   int    // 1-based line number in the source, 0 for read errors
+	Raw    string // The line, truncated to a short snippet
+	Err    error  // Why the line was rejected, e.g. ErrLineTooLong or a JSON syntax error
+}
+
+// RawLine is a line kept as is because it is not a JSON entry.
+type RawLine struct {
+	Source string
+	Line   int
+	Text   string
 }
 
+// Parser reads nabu entries and groups them into traces. Its filters, such as
+// AfterDate or MinLevel, narrow down the entries returned: calling several of them
+// keeps the entries matching all of them. They are evaluated on every frame
+// before frames are grouped into traces, see Parse and KeepWholeTraces.
 type Parser struct {
-	lines     []string
-	afterDate *time.Time
+	sources          []*logSource
+	offsets          map[string]time.Duration // Clock offset of each source, by name
+	afterDate        *time.Time
+	beforeDate       *time.Time
+	filters          []func(Output) bool // Every filter must match for an entry to be kept
+	keepWholeTraces  bool                // Whether traces keep all frames when one matches
+	maxLineSize      int                 // Longer lines are rejected, DefaultMaxLineSize when zero
+	strict           bool                // Whether to stop at the first rejected line
+	keepUnstructured bool                // Whether non-JSON lines are kept instead of rejected
+	resume           *Checkpoint         // Where reading starts, see ResumeFrom
+	holdQuiet        time.Duration       // Traces seen more recently are held in the checkpoint, see HoldOpenTraces
+	workers          int                 // Goroutines decoding each file, one when zero
+	decompress       bool                // Whether readers are decompressed when compressed, see Decompress
 }
@@ -0,0 +1,42 @@
+// Package nabuhttp provides net/http integrations for nabu.
+package nabuhttp
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rah-0/nabu"
+)
+
+// HeaderUuid is the header used to receive and return the correlation UUID.
+const HeaderUuid = "X-Nabu-Uuid"
+
+// Recovery returns a middleware that recovers panics raised by next.
+// Each request is given a UUID, taken from the HeaderUuid request header when present,
+// which is stored in the request context (see nabu.UuidFromContext). When a panic
+// occurs it is logged with that UUID and the full stack, and a 500 response
+// including the UUID is returned so that the failure can be looked up in the logs.
+// http.ErrAbortHandler is propagated untouched, as net/http expects.
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderUuid)
+		if id == "" {
+			id = uuid.NewString()
+		}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			nabu.FromPanic(v).WithUuid(id).WithArgs("Method", r.Method, "Path", r.URL.Path).Log()
+			w.Header().Set(HeaderUuid, id)
+			http.Error(w, "internal server error: "+id, http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r.WithContext(nabu.ContextWithUuid(r.Context(), id)))
+	})
+}
@@ -0,0 +1,78 @@
+package nabuhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rah-0/nabu"
+)
+
+func TestMain(m *testing.M) {
+	nabu.SetLogOutput(nabu.OutputInternal)
+	os.Exit(m.Run())
+}
+
+func TestRecovery(t *testing.T) {
+	var ctxUuid string
+	handler := Recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxUuid = nabu.UuidFromContext(r.Context())
+		panic("handler failed")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", rec.Code)
+	}
+	id := rec.Header().Get(HeaderUuid)
+	if id == "" || id != ctxUuid {
+		t.Errorf("Expected the context UUID %q in the response header, got %q", ctxUuid, id)
+	}
+	if !strings.Contains(rec.Body.String(), id) {
+		t.Errorf("Expected the UUID in the response body, got %q", rec.Body.String())
+	}
+}
+
+func TestRecoveryUsesRequestUuid(t *testing.T) {
+	handler := Recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set(HeaderUuid, "frontend-trace-12345")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get(HeaderUuid) != "frontend-trace-12345" {
+		t.Errorf("Expected the incoming UUID to be reused, got %q", rec.Header().Get(HeaderUuid))
+	}
+}
+
+func TestRecoveryWithoutPanic(t *testing.T) {
+	handler := Recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get(HeaderUuid) != "" {
+		t.Errorf("Expected the response to be untouched, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	handler := Recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler to propagate, got %v", v)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
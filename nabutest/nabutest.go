@@ -0,0 +1,244 @@
+// Package nabutest provides helpers to capture and assert nabu entries in tests.
+package nabutest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rah-0/nabu"
+)
+
+// EnvUpdate is the environment variable that makes Golden rewrite golden files
+// instead of comparing against them, e.g. NABUTEST_UPDATE=1 go test ./...
+const EnvUpdate = "NABUTEST_UPDATE"
+
+// Recorder collects the entries written while a test runs.
+// Each Recorder has its own buffer, so tests do not need to reset shared state,
+// and it is safe to use from several goroutines and under t.Parallel.
+type Recorder struct {
+	t       testing.TB
+	scope   *scope
+	mu      sync.Mutex
+	entries []nabu.Output
+}
+
+// Capture starts recording the entries of the test until it finishes.
+// Entries are attributed to a test explicitly, so that tests running in parallel
+// do not see each other's: the code under test logs with the context returned by
+// Context (see nabu.Logger.WithContext), or the UUID of its entries is passed to
+// Track. The chains of recorded entries, and the chains started in their context,
+// are recorded as well, whichever goroutine writes them. The entries of a subtest
+// are also recorded by its parent test.
+func Capture(t testing.TB) *Recorder {
+	t.Helper()
+	r := &Recorder{t: t}
+	r.scope = listen(t, r.record)
+	return r
+}
+
+// Context returns the context of the test, carrying the UUID the entries of the
+// test are attributed to, e.g. nabu.FromMessage("started").WithContext(ctx).Log().
+// It is canceled when the test finishes, like t.Context.
+func (r *Recorder) Context() context.Context {
+	return r.scope.ctx
+}
+
+// Track also records the entries with the given UUID, or having it as parent
+// UUID, e.g. those of a chain started without the context of the test.
+func (r *Recorder) Track(uuid string) {
+	r.scope.track(uuid)
+}
+
+func (r *Recorder) record(o nabu.Output) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, o)
+}
+
+// Entries returns a copy of the entries recorded so far, in the order they were written.
+func (r *Recorder) Entries() []nabu.Output {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]nabu.Output, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// Reset discards the entries recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// HasEntry reports whether an entry, or a frame of a deferred chain, has the given
+// level and a message containing msgSubstring. The test is marked as failed otherwise.
+func (r *Recorder) HasEntry(level nabu.LogLevel, msgSubstring string) bool {
+	r.t.Helper()
+	for _, o := range r.frames() {
+		if o.Level == level && strings.Contains(o.Msg, msgSubstring) {
+			return true
+		}
+	}
+	r.t.Errorf("nabutest: no %v entry with message containing %q among %d entries", level, msgSubstring, len(r.Entries()))
+	return false
+}
+
+// ChainLen returns the number of frames recorded for the chain with the given UUID,
+// counting both frames logged one by one and frames of deferred chain entries.
+func (r *Recorder) ChainLen(uuid string) int {
+	n := 0
+	for _, o := range r.Entries() {
+		if o.UUID != uuid {
+			continue
+		}
+		if len(o.Chain) > 0 {
+			n += len(o.Chain)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// NoErrors reports whether no entry was written at LevelError or above and no entry
+// carries an error. The test is marked as failed otherwise, listing the offending entries.
+func (r *Recorder) NoErrors() bool {
+	r.t.Helper()
+	ok := true
+	for _, o := range r.Entries() {
+		if o.Level >= nabu.LevelError || o.Error != "" || len(o.Errors) > 0 {
+			r.t.Errorf("nabutest: unexpected error entry: %s", marshal(o))
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Golden compares the recorded entries with the golden file at path, one JSON entry
+// per line. UUIDs are replaced by stable placeholders ("uuid-1", "uuid-2", ...) in
+// order of appearance, Seq is renumbered from 1 keeping the order of the entries,
+// and Date and Stack are replaced as well, so that the file does not change between runs. The test is marked as failed when they differ.
+// When NABUTEST_UPDATE is set, the file is written instead.
+func (r *Recorder) Golden(path string) bool {
+	r.t.Helper()
+	got := normalize(r.Entries())
+
+	if os.Getenv(EnvUpdate) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatalf("nabutest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			r.t.Fatalf("nabutest: %v", err)
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		r.t.Errorf("nabutest: reading golden file (set %s=1 to create it): %v", EnvUpdate, err)
+		return false
+	}
+	if !bytes.Equal(got, want) {
+		r.t.Errorf("nabutest: entries differ from %s (set %s=1 to update)\ngot:\n%s\nwant:\n%s", path, EnvUpdate, got, want)
+		return false
+	}
+	return true
+}
+
+// frames returns the recorded entries with deferred chains expanded into their frames.
+func (r *Recorder) frames() []nabu.Output {
+	var frames []nabu.Output
+	for _, o := range r.Entries() {
+		frames = append(frames, o)
+		frames = append(frames, o.Chain...)
+	}
+	return frames
+}
+
+// normalize renders entries as JSON lines, with run-dependent values replaced.
+func normalize(entries []nabu.Output) []byte {
+	uuids := make(map[string]string)
+	placeholder := func(uuid string) string {
+		if uuid == "" {
+			return ""
+		}
+		if _, ok := uuids[uuid]; !ok {
+			uuids[uuid] = "uuid-" + strconv.Itoa(len(uuids)+1)
+		}
+		return uuids[uuid]
+	}
+
+	var seqs []uint64
+	for _, o := range entries {
+		seqs = append(seqs, o.Seq)
+		for _, f := range o.Chain {
+			seqs = append(seqs, f.Seq)
+		}
+	}
+	slices.Sort(seqs)
+	seqs = slices.Compact(seqs)
+	seq := func(s uint64) uint64 {
+		if s == 0 {
+			return 0
+		}
+		i, _ := slices.BinarySearch(seqs, s)
+		if seqs[0] == 0 {
+			return uint64(i)
+		}
+		return uint64(i + 1)
+	}
+
+	var buf bytes.Buffer
+	for _, o := range entries {
+		o.UUID = placeholder(o.UUID)
+		o.ParentUUID = placeholder(o.ParentUUID)
+		o.Seq = seq(o.Seq)
+		related := make([]string, len(o.Related))
+		for i, uuid := range o.Related {
+			related[i] = placeholder(uuid)
+		}
+		if len(related) > 0 {
+			o.Related = related
+		}
+		if o.Date != "" {
+			o.Date = "<date>"
+		}
+		if o.Stack != "" {
+			o.Stack = "<stack>"
+		}
+		chain := make([]nabu.Output, len(o.Chain))
+		for i, f := range o.Chain {
+			if f.Date != "" {
+				f.Date = "<date>"
+			}
+			f.Seq = seq(f.Seq)
+			chain[i] = f
+		}
+		if len(chain) > 0 {
+			o.Chain = chain
+		}
+		buf.WriteString(marshal(o))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// marshal renders an entry as JSON without escaping HTML characters such as '<'.
+func marshal(o nabu.Output) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(o); err != nil {
+		return err.Error()
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
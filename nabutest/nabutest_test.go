@@ -0,0 +1,229 @@
+package nabutest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rah-0/nabu"
+)
+
+func TestMain(m *testing.M) {
+	nabu.SetLogOutput(nabu.OutputInternal)
+	os.Exit(m.Run())
+}
+
+// fakeTB records failures instead of failing the real test.
+type fakeTB struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func TestCapture(t *testing.T) {
+	t.Parallel()
+	rec := Capture(t)
+	ctx := rec.Context()
+
+	nabu.FromMessage("capture: started").WithContext(ctx).Log()
+	err := nabu.FromError(errors.New("capture: root")).WithContext(ctx).WithMessage("capture: inner").Log()
+	err = nabu.FromError(err).WithMessage("capture: outer").Log()
+	nabu.FromMessage("capture: unattributed").Log()
+
+	if !rec.HasEntry(nabu.LevelInfo, "capture: started") {
+		return
+	}
+	rec.HasEntry(nabu.LevelError, "capture: outer")
+	if countMsg(rec.Entries(), "capture: unattributed") != 0 {
+		t.Error("Expected entries without the context of the test not to be recorded")
+	}
+
+	var x *nabu.Logger
+	errors.As(err, &x)
+	if n := rec.ChainLen(x.UUID); n != 2 {
+		t.Errorf("Expected a chain of 2 frames, got %d", n)
+	}
+}
+
+func TestCaptureIsolatedBuffers(t *testing.T) {
+	t.Parallel()
+	first := Capture(t)
+	ctx := first.Context()
+	nabu.FromMessage("isolated: before second").WithContext(ctx).Log()
+	second := Capture(t)
+	nabu.FromMessage("isolated: after second").WithContext(second.Context()).Log()
+
+	if second.Context() != ctx {
+		t.Error("Expected the recorders of a test to share its context")
+	}
+	if countMsg(second.Entries(), "isolated: before second") != 0 {
+		t.Error("Expected a new recorder not to see earlier entries")
+	}
+	if countMsg(first.Entries(), "isolated: after second") != 1 {
+		t.Error("Expected the first recorder to keep recording")
+	}
+
+	first.Reset()
+	if countMsg(first.Entries(), "isolated") != 0 {
+		t.Error("Expected Reset to discard entries")
+	}
+}
+
+func TestCaptureStopsAfterTest(t *testing.T) {
+	var rec *Recorder
+	t.Run("inner", func(t *testing.T) {
+		rec = Capture(t)
+		nabu.FromMessage("cleanup: during").WithContext(rec.Context()).Log()
+	})
+	nabu.FromMessage("cleanup: after").WithContext(rec.Context()).Log()
+
+	if countMsg(rec.Entries(), "cleanup: during") != 1 || countMsg(rec.Entries(), "cleanup: after") != 0 {
+		t.Errorf("Expected recording to stop with the test, got %v", rec.Entries())
+	}
+	if rec.Context().Err() == nil {
+		t.Error("Expected the context to be canceled with the test")
+	}
+}
+
+func TestCaptureParallelTests(t *testing.T) {
+	recs := make([]*Recorder, 4)
+	t.Run("group", func(t *testing.T) {
+		for i := range recs {
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				recs[i] = Capture(t)
+				ctx := recs[i].Context()
+				for j := 0; j < 20; j++ {
+					nabu.FromMessage(fmt.Sprintf("parallel: test %d", i)).WithContext(ctx).Log()
+				}
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					nabu.FromMessage(fmt.Sprintf("parallel: test %d goroutine", i)).WithContext(ctx).Log()
+				}()
+				<-done
+			})
+		}
+	})
+
+	for i, rec := range recs {
+		if n := countMsg(rec.Entries(), "parallel: "); n != 21 {
+			t.Errorf("Expected test %d to record its 21 entries only, got %d", i, n)
+		}
+		if n := countMsg(rec.Entries(), fmt.Sprintf("parallel: test %d", i)); n != 21 {
+			t.Errorf("Expected test %d to record its own entries, got %d", i, n)
+		}
+	}
+}
+
+func TestCaptureSubtests(t *testing.T) {
+	rec := Capture(t)
+	var sub *Recorder
+	t.Run("sub", func(t *testing.T) {
+		sub = Capture(t)
+		nabu.FromMessage("subtest: inner").WithContext(sub.Context()).Log()
+	})
+	nabu.FromMessage("subtest: outer").WithContext(rec.Context()).Log()
+
+	if countMsg(rec.Entries(), "subtest: ") != 2 {
+		t.Errorf("Expected the parent test to record its subtests, got %v", rec.Entries())
+	}
+	if countMsg(sub.Entries(), "subtest: ") != 1 {
+		t.Errorf("Expected the subtest to record its own entries only, got %v", sub.Entries())
+	}
+}
+
+func TestCaptureTrack(t *testing.T) {
+	rec := Capture(t)
+	uuid := "3c6f0e7a-track"
+	rec.Track(uuid)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		nabu.FromMessage("track: untracked").Log()
+		nabu.FromMessage("track: tracked").WithUuid(uuid).Log()
+		// Chains started in the context of a tracked chain follow it
+		nabu.FromMessage("track: child").WithContext(nabu.ContextWithUuid(context.Background(), uuid)).Log()
+	}()
+	<-done
+
+	entries := rec.Entries()
+	if countMsg(entries, "track: untracked") != 0 || countMsg(entries, "track: tracked") != 1 || countMsg(entries, "track: child") != 1 {
+		t.Errorf("Expected only the tracked entries to be recorded, got %v", entries)
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	t.Parallel()
+	tb := &fakeTB{TB: t}
+	rec := &Recorder{t: tb}
+	rec.record(nabu.Output{Msg: "failure: query", Error: "EOF", Level: nabu.LevelError})
+	rec.record(nabu.Output{Msg: "failure: warn", Level: nabu.LevelWarn})
+
+	if rec.HasEntry(nabu.LevelInfo, "failure: query") {
+		t.Error("Expected HasEntry to fail on a level mismatch")
+	}
+	if rec.NoErrors() {
+		t.Error("Expected NoErrors to fail")
+	}
+	if len(tb.failures) != 2 {
+		t.Errorf("Expected 2 reported failures, got %v", tb.failures)
+	}
+}
+
+func TestDeferredChain(t *testing.T) {
+	nabu.SetDeferredChains(true)
+	defer nabu.SetDeferredChains(false)
+	rec := Capture(t)
+
+	err := nabu.FromError(errors.New("deferred: root")).WithContext(rec.Context()).WithMessage("deferred: inner").Log()
+	err = nabu.FromError(err).WithMessage("deferred: boundary").Boundary().Log()
+
+	var x *nabu.Logger
+	errors.As(err, &x)
+	if n := rec.ChainLen(x.UUID); n != 2 {
+		t.Errorf("Expected a chain of 2 frames, got %d", n)
+	}
+	rec.HasEntry(nabu.LevelError, "deferred: inner")
+}
+
+func TestGolden(t *testing.T) {
+	rec := &Recorder{t: t}
+	rec.record(nabu.Output{UUID: "9f1c", Date: "2025-06-25 01:01:00.000000", Seq: 1040, Error: "EOF", Msg: "read", Function: "main.read", Line: 3, Level: nabu.LevelError})
+	rec.record(nabu.Output{UUID: "9f1c", Date: "2025-06-25 01:01:00.000100", Seq: 1045, Msg: "handle", Function: "main.handle", Line: 9, Level: nabu.LevelError})
+	rec.record(nabu.Output{UUID: "77aa", Date: "2025-06-25 01:01:01.000000", Seq: 1046, Related: []string{"9f1c"}, ParentUUID: "51b0", Msg: "joined", Stack: "goroutine 1", Level: nabu.LevelFatal})
+
+	rec.Golden(filepath.Join("testdata", "golden.ndjson"))
+
+	// A differing golden file is reported
+	t.Setenv(EnvUpdate, "")
+	tb := &fakeTB{TB: t}
+	rec.t = tb
+	path := filepath.Join(t.TempDir(), "other.ndjson")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Golden(path) || len(tb.failures) != 1 {
+		t.Errorf("Expected a mismatch to be reported, got %v", tb.failures)
+	}
+}
+
+func countMsg(entries []nabu.Output, prefix string) int {
+	n := 0
+	for _, o := range entries {
+		if strings.HasPrefix(o.Msg, prefix) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+package nabutest
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rah-0/nabu"
+)
+
+// scope gathers what is attributed to one test: the entries whose UUID, parent
+// UUID or related UUIDs are the UUID of the test or a UUID attributed to it since.
+type scope struct {
+	t         testing.TB
+	ctx       context.Context     // Context of the test, carrying its UUID
+	parent    *scope              // Scope of the enclosing test, which receives the entries as well
+	uuids     []string            // UUIDs attributed to the test, the first one being its own
+	listeners []func(nabu.Output) // Never appended to in place, so that dispatch can call them unlocked
+}
+
+// scopes routes entries to the tests they are attributed to. A single sink is
+// added while at least one test listens.
+var scopes struct {
+	mu     sync.Mutex
+	byTest map[testing.TB]*scope
+	byUuid map[string][]*scope
+	remove func()
+}
+
+// scopeOf returns the scope of the test, created on first use and dropped when the test finishes.
+// scopes.mu must be held.
+func scopeOf(t testing.TB) *scope {
+	if s, ok := scopes.byTest[t]; ok {
+		return s
+	}
+	if scopes.byTest == nil {
+		scopes.byTest = make(map[testing.TB]*scope)
+		scopes.byUuid = make(map[string][]*scope)
+	}
+
+	id := uuid.NewString()
+	s := &scope{t: t, ctx: nabu.ContextWithUuid(t.Context(), id)}
+	for other := range scopes.byTest {
+		// Subtests are named after their parent, e.g. TestParent/sub
+		if name := other.Name(); strings.HasPrefix(t.Name(), name+"/") && (s.parent == nil || len(name) > len(s.parent.t.Name())) {
+			s.parent = scopes.byTest[other]
+		}
+	}
+	scopes.byTest[t] = s
+	s.attribute(id)
+	if len(scopes.byTest) == 1 {
+		scopes.remove = nabu.AddSink(nabu.SinkFunc(dispatch))
+	}
+	t.Cleanup(s.drop)
+	return s
+}
+
+// listen hands fn the entries attributed to the test until it finishes, and returns its scope.
+func listen(t testing.TB, fn func(o nabu.Output)) *scope {
+	scopes.mu.Lock()
+	defer scopes.mu.Unlock()
+	s := scopeOf(t)
+	s.listeners = append(slices.Clip(s.listeners), fn)
+	return s
+}
+
+// track attributes the entries carrying uuid to the scope.
+func (s *scope) track(uuid string) {
+	scopes.mu.Lock()
+	defer scopes.mu.Unlock()
+	if scopes.byTest[s.t] == s {
+		s.attribute(uuid)
+	}
+}
+
+// attribute adds uuid to the scope, unless empty or already there. scopes.mu must be held.
+func (s *scope) attribute(uuid string) {
+	if uuid == "" || slices.Contains(s.uuids, uuid) {
+		return
+	}
+	s.uuids = append(s.uuids, uuid)
+	scopes.byUuid[uuid] = append(slices.Clip(scopes.byUuid[uuid]), s)
+}
+
+func (s *scope) drop() {
+	scopes.mu.Lock()
+	defer scopes.mu.Unlock()
+	delete(scopes.byTest, s.t)
+	for _, uuid := range s.uuids {
+		others := slices.DeleteFunc(slices.Clone(scopes.byUuid[uuid]), func(x *scope) bool { return x == s })
+		if len(others) == 0 {
+			delete(scopes.byUuid, uuid)
+		} else {
+			scopes.byUuid[uuid] = others
+		}
+	}
+	if len(scopes.byTest) == 0 {
+		scopes.remove()
+		scopes.remove = nil
+	}
+}
+
+// dispatch is the sink handing every entry to the tests it is attributed to, and
+// to their enclosing tests. The UUID of an attributed entry is attributed to its
+// tests as well, so that the rest of its chain, and the chains started in its
+// context, follow.
+func dispatch(o nabu.Output) {
+	scopes.mu.Lock()
+	var owners []*scope
+	for _, uuid := range append([]string{o.UUID, o.ParentUUID}, o.Related...) {
+		for _, s := range scopes.byUuid[uuid] {
+			for ; s != nil && scopes.byTest[s.t] == s; s = s.parent {
+				if !slices.Contains(owners, s) {
+					owners = append(owners, s)
+				}
+			}
+		}
+	}
+	var listeners []func(nabu.Output)
+	for _, s := range owners {
+		s.attribute(o.UUID)
+		listeners = append(listeners, s.listeners...)
+	}
+	scopes.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(o)
+	}
+}
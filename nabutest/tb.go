@@ -0,0 +1,38 @@
+package nabutest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/rah-0/nabu"
+)
+
+// TB writes the entries of the test through t.Log, rendered with nabu.FormatConsole,
+// so that the entries produced during a test appear next to its own output when it
+// fails (or with go test -v), instead of on stderr for the whole package run.
+// Entries are attributed to the test as with Capture, through the returned context,
+// which is the same as the one of the Recorders of the test.
+// Writing stops once the test finishes, so goroutines that outlive the test can
+// keep logging without triggering "Log in goroutine after Test has completed" panics.
+func TB(t testing.TB) context.Context {
+	t.Helper()
+
+	var mu sync.Mutex
+	finished := false
+	s := listen(t, func(o nabu.Output) {
+		mu.Lock()
+		defer mu.Unlock()
+		if finished {
+			return
+		}
+		t.Log(nabu.FormatConsole(o))
+	})
+
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		finished = true
+	})
+	return s.ctx
+}
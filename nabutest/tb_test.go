@@ -0,0 +1,119 @@
+package nabutest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rah-0/nabu"
+)
+
+// logTB records t.Log calls and cleanups instead of forwarding them to the real test.
+type logTB struct {
+	testing.TB
+	mu       sync.Mutex
+	logs     []string
+	cleanups []func()
+}
+
+func (l *logTB) Helper() {}
+
+func (l *logTB) Log(args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, fmt.Sprint(args...))
+}
+
+func (l *logTB) Cleanup(f func()) {
+	l.cleanups = append(l.cleanups, f)
+}
+
+func (l *logTB) finish() {
+	for i := len(l.cleanups) - 1; i >= 0; i-- {
+		l.cleanups[i]()
+	}
+}
+
+func (l *logTB) count(substring string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, s := range l.logs {
+		if strings.Contains(s, substring) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestTB(t *testing.T) {
+	tb := &logTB{TB: t}
+	ctx := TB(tb)
+
+	nabu.FromMessage("tb: during").WithContext(ctx).WithArgs("k", 1).Log()
+	nabu.FromMessage("tb: unattributed").Log()
+	tb.finish()
+	nabu.FromMessage("tb: after").WithContext(ctx).Log()
+
+	if tb.count("tb: during") != 1 {
+		t.Errorf("Expected the entry to be written through t.Log, got %v", tb.logs)
+	}
+	if tb.count("tb: after") != 0 || tb.count("tb: unattributed") != 0 {
+		t.Errorf("Expected nothing to be written once the test finished, got %v", tb.logs)
+	}
+	if !strings.Contains(tb.logs[0], `INFO tb: during args=["k",1]`) {
+		t.Errorf("Expected the console format, got %q", tb.logs[0])
+	}
+}
+
+func TestTBConcurrentAfterFinish(t *testing.T) {
+	tb := &logTB{TB: t}
+	ctx := TB(tb)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				nabu.FromMessage("tb: concurrent").WithContext(ctx).Log()
+			}
+		}()
+	}
+	tb.finish()
+	written := tb.count("tb: concurrent")
+	wg.Wait()
+
+	if tb.count("tb: concurrent") != written {
+		t.Error("Expected no entry to be written after the cleanup returned")
+	}
+}
+
+func TestTBReal(t *testing.T) {
+	ctx := TB(t)
+	nabu.FromMessage("tb: visible with go test -v").WithContext(ctx).Log()
+}
+
+func TestTBParallelTests(t *testing.T) {
+	tbs := make([]*logTB, 4)
+	t.Run("group", func(t *testing.T) {
+		for i := range tbs {
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				tbs[i] = &logTB{TB: t}
+				ctx := TB(tbs[i])
+				for j := 0; j < 20; j++ {
+					nabu.FromMessage(fmt.Sprintf("tb parallel: test %d", i)).WithContext(ctx).Log()
+				}
+				tbs[i].finish()
+			})
+		}
+	})
+
+	for i, tb := range tbs {
+		if tb.count("tb parallel: ") != 20 || tb.count(fmt.Sprintf("tb parallel: test %d", i)) != 20 {
+			t.Errorf("Expected test %d to log its 20 entries only, got %d", i, tb.count("tb parallel: "))
+		}
+	}
+}
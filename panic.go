@@ -0,0 +1,98 @@
+package nabu
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// FromPanic creates a Logger from a recovered panic value.
+// It must be called from the deferred function that recovered the panic, so that
+// the full goroutine stack and the function that panicked can be captured.
+// Error values are kept as CausedBy (preserving the UUID of a panicking *Logger),
+// other values are converted with fmt. The level is set by SetPanicLevel.
+// The entry is always written by Log, even when chains are deferred.
+func FromPanic(v any) *Logger {
+	err, ok := v.(error)
+	if !ok {
+		err = fmt.Errorf("panic: %v", v)
+	}
+
+	x := FromError(err)
+	x.Msg = "panic recovered"
+	x.Level, _ = getPanicConfig()
+	x.stack = string(debug.Stack())
+	x.function, x.line = panicSite()
+	x.enableStackTrace = false // Keep the panic site instead of the caller of Log
+	x.boundary = true
+	return x
+}
+
+// Recover logs a panic and then swallows it or panics again (see SetPanicRepanic).
+// It must be deferred directly: defer nabu.Recover()
+func Recover() {
+	if v := recover(); v != nil {
+		handlePanic(context.Background(), v)
+	}
+}
+
+// RecoverContext behaves like Recover and uses the UUID stored in ctx (see ContextWithUuid)
+// so the panic is correlated with the rest of the chain.
+// It must be deferred directly: defer nabu.RecoverContext(ctx)
+func RecoverContext(ctx context.Context) {
+	if v := recover(); v != nil {
+		handlePanic(ctx, v)
+	}
+}
+
+// Go runs fn in a new goroutine whose panics are logged as with Recover.
+func Go(fn func()) {
+	go func() {
+		defer Recover()
+		fn()
+	}()
+}
+
+// GoContext runs fn in a new goroutine whose panics are logged as with RecoverContext.
+func GoContext(ctx context.Context, fn func(ctx context.Context)) {
+	go func() {
+		defer RecoverContext(ctx)
+		fn(ctx)
+	}()
+}
+
+// handlePanic logs a recovered panic and panics again if configured to.
+func handlePanic(ctx context.Context, v any) {
+	x := FromPanic(v)
+	if uuid := UuidFromContext(ctx); uuid != "" {
+		x.UUID = uuid
+	}
+	x.Log()
+
+	if _, repanic := getPanicConfig(); repanic {
+		panic(v)
+	}
+}
+
+// panicSite returns the function and line that panicked.
+// It looks for the first non-runtime frame after the runtime panic machinery.
+func panicSite() (string, int) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs) // Ignore: runtime.Callers, panicSite
+	frames := runtime.CallersFrames(pcs[:n])
+
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" || frame.Function == "runtime.sigpanic" {
+			panicking = true
+		} else if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+			return frame.Function, frame.Line
+		}
+		if !more {
+			return "", 0
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package nabu
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	resetTestState()
+
+	func() {
+		defer Recover()
+		panicWithValue("boom")
+	}()
+
+	entry := fromJson(getInternalOutput())
+	if entry == nil {
+		t.Fatal("Expected the panic to be logged")
+	}
+	if entry.Error != "panic: boom" || entry.Msg != "panic recovered" || entry.Level != LevelFatal {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Function != "github.com/rah-0/nabu.panicWithValue" {
+		t.Errorf("Expected the panicking function, got %q", entry.Function)
+	}
+	if entry.UUID == "" {
+		t.Error("Expected a UUID")
+	}
+	if !strings.Contains(entry.Stack, "goroutine ") || !strings.Contains(entry.Stack, "panicWithValue") {
+		t.Errorf("Expected the full goroutine stack, got %q", entry.Stack)
+	}
+}
+
+func TestRecoverRuntimeError(t *testing.T) {
+	resetTestState()
+
+	func() {
+		defer Recover()
+		panicWithNilMap()
+	}()
+
+	entry := fromJson(getInternalOutput())
+	if entry == nil || !strings.Contains(entry.Error, "assignment to entry in nil map") {
+		t.Fatalf("Unexpected entry: %s", getInternalOutput())
+	}
+	if entry.Function != "github.com/rah-0/nabu.panicWithNilMap" {
+		t.Errorf("Expected the panicking function, got %q", entry.Function)
+	}
+}
+
+func TestRecoverContextAndLevel(t *testing.T) {
+	SetPanicLevel(LevelError)
+	defer SetPanicLevel(LevelFatal)
+	resetTestState()
+
+	ctx := ContextWithUuid(context.Background(), "request-uuid")
+	func() {
+		defer RecoverContext(ctx)
+		panic(errors.New("typed error"))
+	}()
+
+	entry := fromJson(getInternalOutput())
+	if entry == nil || entry.UUID != "request-uuid" {
+		t.Fatalf("Expected the context UUID, got %s", getInternalOutput())
+	}
+	if entry.Error != "typed error" || entry.Level != LevelError {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	SetPanicRepanic(true)
+	defer SetPanicRepanic(false)
+	resetTestState()
+
+	defer func() {
+		if v := recover(); v != "again" {
+			t.Errorf("Expected the original value to be re-panicked, got %v", v)
+		}
+		if getInternalOutput() == "" {
+			t.Error("Expected the panic to be logged before re-panicking")
+		}
+	}()
+
+	defer Recover()
+	panic("again")
+}
+
+func TestGo(t *testing.T) {
+	resetTestState()
+
+	// The panics are logged once fn returned, so wait for the entries themselves
+	var wg sync.WaitGroup
+	wg.Add(2)
+	remove := AddSink(SinkFunc(func(o Output) {
+		if o.Msg == "panic recovered" {
+			wg.Done()
+		}
+	}))
+	defer remove()
+	Go(func() {
+		panicWithValue("in goroutine")
+	})
+	GoContext(ContextWithUuid(context.Background(), "worker-uuid"), func(ctx context.Context) {
+		panicWithValue("in goroutine with context")
+	})
+	wg.Wait()
+
+	output := getInternalOutput()
+	if !strings.Contains(output, "panic: in goroutine") || !strings.Contains(output, `"UUID":"worker-uuid"`) {
+		t.Errorf("Expected both panics to be logged, got: %s", output)
+	}
+}
+
+func TestRecoverDeferredChains(t *testing.T) {
+	SetDeferredChains(true)
+	defer SetDeferredChains(false)
+	resetTestState()
+
+	inner := FromError(errors.New("root")).WithMessage("inner").Log()
+	func() {
+		defer Recover()
+		panic(inner)
+	}()
+
+	entry := fromJson(getInternalOutput())
+	if entry == nil || len(entry.Chain) != 2 || entry.Error != "root" {
+		t.Fatalf("Expected the panic to be written with its chain, got %s", getInternalOutput())
+	}
+	if entry.UUID != inner.(*Logger).UUID {
+		t.Error("Expected the UUID of the panicking Logger to be preserved")
+	}
+}
+
+func panicWithValue(v string) {
+	panic(v)
+}
+
+func panicWithNilMap() {
+	var m map[string]int
+	m["x"] = 1
+}
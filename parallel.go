@@ -0,0 +1,152 @@
+package nabu
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// chunkSize is the amount of a file decoded at once by a worker, see Workers.
+var chunkSize int64 = 4 << 20
+
+// Workers decodes files on n goroutines instead of one: each file is split into
+// line-aligned chunks, whose lines are decoded concurrently and then handed over
+// in file order, so that the result is the same as without Workers. n <= 0 uses
+// runtime.GOMAXPROCS. Other inputs are decoded sequentially.
+func (p *Parser) Workers(n int) *Parser {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	p.workers = n
+	return p
+}
+
+// decodedLine is a line passed through decodeLine, and where it ends.
+type decodedLine struct {
+	pos   scanPosition
+	entry dated
+	err   error
+	ok    bool // False for blank lines
+}
+
+// chunk is a line-aligned part of a file, decoded by one worker.
+type chunk struct {
+	start, end int64
+	lines      []decodedLine // Numbered from the start of the chunk
+	err        error
+	done       chan struct{} // Closed once decoded
+}
+
+// decodeChunks decodes the file of src from pos on p.workers goroutines, and calls
+// fn with each line in file order, until fn returns false. pos is kept up to date
+// as with scanLines. At most two chunks per worker are held in memory.
+func (p *Parser) decodeChunks(src *logSource, pos *scanPosition, fn func(decodedLine) bool) error {
+	f, err := os.Open(src.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size() // Lines appended meanwhile are left for the next run
+
+	ordered := make(chan *chunk, 2*p.workers)
+	jobs := make(chan *chunk)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(stop)
+
+	wg.Add(1)
+	go func() { // Split the file
+		defer wg.Done()
+		defer close(jobs)
+		defer close(ordered)
+		for start := pos.offset; start < size; {
+			end, err := nextLineStart(f, start+chunkSize, size)
+			c := &chunk{start: start, end: end, err: err, done: make(chan struct{})}
+			for _, ch := range []chan *chunk{ordered, jobs} {
+				select {
+				case ch <- c:
+				case <-stop:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+			start = end
+		}
+	}()
+
+	limit := p.lineLimit()
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				if c.err == nil {
+					p.decodeChunk(f, src.label(), limit, c)
+				}
+				close(c.done)
+			}
+		}()
+	}
+
+	base := pos.line
+	for c := range ordered {
+		<-c.done
+		if c.err != nil {
+			return c.err
+		}
+		for _, line := range c.lines {
+			line.pos.line += base
+			if pe, ok := line.err.(*ParseError); ok {
+				renumbered := *pe
+				renumbered.Line = line.pos.line
+				line.err = &renumbered
+			}
+			*pos = line.pos
+			if !fn(line) {
+				return nil
+			}
+		}
+		base += len(c.lines)
+		c.lines = nil
+	}
+	return nil
+}
+
+// decodeChunk decodes the lines of a chunk.
+func (p *Parser) decodeChunk(f *os.File, source string, limit int, c *chunk) {
+	pos := scanPosition{offset: c.start}
+	c.err = scanReader(io.NewSectionReader(f, c.start, c.end-c.start), limit, &pos, func(lineNo int, line string, tooLong bool) bool {
+		entry, ok, err := p.decodeLine(source, lineNo, line, tooLong)
+		c.lines = append(c.lines, decodedLine{pos: pos, entry: entry, err: err, ok: ok})
+		return true
+	})
+}
+
+// nextLineStart returns the offset of the first line starting at or after from,
+// or size when there is none.
+func nextLineStart(f *os.File, from, size int64) (int64, error) {
+	if from >= size {
+		return size, nil
+	}
+	buf := make([]byte, 64<<10)
+	for off := from - 1; off < size; off += int64(len(buf)) {
+		n, err := f.ReadAt(buf, off)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return off + int64(i) + 1, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+	}
+	return size, nil
+}
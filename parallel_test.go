@@ -0,0 +1,126 @@
+package nabu
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFixture writes about size bytes of logs: entries, traces of several frames,
+// deferred chains, and a few blank, broken and long lines.
+func writeFixture(t testing.TB, path string, size int64) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	start, _ := time.Parse(TimeLayout, "2025-06-25 01:00:00.000000")
+
+	var written int64
+	for i := 0; written < size; i++ {
+		date := start.Add(time.Duration(i/3) * time.Millisecond).Format(TimeLayout) // Shared by consecutive entries
+		var line string
+		switch {
+		case i%997 == 0:
+			line = "panic: " + strings.Repeat("x", i%300)
+		case i%991 == 0:
+			line = ""
+		case i%983 == 0:
+			line = `{"Msg":"` + strings.Repeat("long ", 100) + `"}`
+		case i%31 == 0:
+			line = fmt.Sprintf(`{"UUID":"d%d","Date":"%s","Seq":%d,"Error":"timeout after %dms","Level":3,"Chain":[{"Function":"db.Query","Line":10},{"Function":"main.run","Line":20}]}`, i, date, i, i%500)
+		case i%3 == 0:
+			line = fmt.Sprintf(`{"Date":"%s","Seq":%d,"Msg":"request %d","Args":["userID",%d],"Function":"http.serve","Line":42,"Level":1}`, date, i, i, i%100)
+		default:
+			uuid := strconv.Itoa(i / 12) // Frames of a trace spread over several lines
+			line = fmt.Sprintf(`{"UUID":"%s","Date":"%s","Seq":%d,"Error":"user %d not found","Function":"svc.Handle%d","Line":%d,"Level":3}`, uuid, date, i, i%7, i%4, i%90)
+		}
+		n, _ := w.WriteString(line + "\n")
+		written += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func parseFixture(t testing.TB, path string, configure func(*Parser) *Parser) ParsedLogs {
+	t.Helper()
+	p, err := NewParser().FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configure(p).Parse()
+}
+
+func TestParserWorkers(t *testing.T) {
+	defer func(size int64) { chunkSize = size }(chunkSize)
+	chunkSize = 1 << 10
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFixture(t, path, 512<<10)
+	configs := map[string]func(*Parser) *Parser{
+		"default": func(p *Parser) *Parser { return p },
+		"filters": func(p *Parser) *Parser {
+			return p.MinLevel(LevelWarn).ErrorMatches(regexp.MustCompile("timeout")).KeepWholeTraces()
+		},
+		"maxLineSize": func(p *Parser) *Parser { return p.MaxLineSize(300).KeepUnstructured() },
+		"strict":      func(p *Parser) *Parser { return p.Strict() },
+		"resume": func(p *Parser) *Parser {
+			return p.ResumeFrom(Checkpoint{Offset: 100 << 10, Line: 1000}).HoldOpenTraces(time.Second)
+		},
+	}
+	for name, configure := range configs {
+		want := parseFixture(t, path, configure)
+		for _, workers := range []int{0, 3} {
+			got := parseFixture(t, path, func(p *Parser) *Parser { return configure(p).Workers(workers) })
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected %d workers to match the sequential result", name, workers)
+			}
+		}
+	}
+
+	// Stopping early releases the workers
+	p, _ := NewParser().FromFile(path)
+	count := 0
+	for range p.Workers(4).Entries() {
+		if count++; count == 10 {
+			break
+		}
+	}
+}
+
+func BenchmarkParseFile(b *testing.B) {
+	size := int64(1 << 30) // Set NABU_BENCH_MB for a different size
+	if mb, err := strconv.Atoi(os.Getenv("NABU_BENCH_MB")); err == nil {
+		size = int64(mb) << 20
+	}
+	path := filepath.Join(b.TempDir(), "app.log")
+	writeFixture(b, path, size)
+	info, _ := os.Stat(path)
+
+	for _, workers := range []int{1, 0} {
+		name := "sequential"
+		if workers == 0 {
+			name = "workers"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(info.Size())
+			for b.Loop() {
+				p, _ := NewParser().FromFile(path)
+				if workers == 0 {
+					p.Workers(0)
+				}
+				p.Parse()
+			}
+		})
+	}
+}
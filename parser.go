@@ -1,93 +1,566 @@
 package nabu
 
 import (
-	"bufio"
+	"container/list"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"iter"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 )
 
+// DefaultMaxLineSize is the longest line accepted by a Parser unless MaxLineSize is set.
+const DefaultMaxLineSize = 1 << 20
+
+// rawSnippetSize is how much of a rejected line is kept in ParseError.Raw.
+const rawSnippetSize = 256
+
+var (
+	// ErrLineTooLong is reported for lines longer than the maximum line size.
+	ErrLineTooLong = errors.New("line too long")
+
+	// ErrUnstructured is reported for lines that are not JSON objects.
+	ErrUnstructured = errors.New("not a JSON entry")
+)
+
 func NewParser() *Parser {
 	return &Parser{}
 }
 
+// FromReader reads lines from r. The reader is consumed lazily by Parse, Entries
+// or Traces, so it must remain open until then and can only be parsed once.
 func (p *Parser) FromReader(r io.Reader) *Parser {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		p.lines = append(p.lines, scanner.Text())
-	}
+	p.sources = []*logSource{{reader: r}}
 	return p
 }
 
+// FromFile reads lines from the file at path. The file is opened each time
+// Parse, Entries or Traces runs, and closed once it has been read. An error is
+// returned when it cannot be opened now; when it can no longer be opened later,
+// the error is reported in ParsedLogs.Errors, as a ParseError with Line 0.
 func (p *Parser) FromFile(path string) (*Parser, error) {
+	if err := checkReadable(path); err != nil {
+		return nil, err
+	}
+	p.sources = []*logSource{{path: path}}
+	return p, nil
+}
+
+// checkReadable returns the error opening the file at path for reading, if any.
+func checkReadable(path string) error {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer f.Close()
-	return p.FromReader(f), nil
+	return f.Close()
 }
 
 func (p *Parser) FromString(content string) *Parser {
-	p.lines = strings.Split(content, "\n")
-	return p
+	return p.FromLines(strings.Split(content, "\n"))
 }
 
 func (p *Parser) FromLines(lines []string) *Parser {
-	p.lines = lines
+	p.sources = []*logSource{{lines: lines}}
+	return p
+}
+
+// MaxLineSize sets the length in bytes above which a line is rejected with
+// ErrLineTooLong. Reading continues with the next line. Defaults to DefaultMaxLineSize.
+func (p *Parser) MaxLineSize(n int) *Parser {
+	p.maxLineSize = n
 	return p
 }
 
-func (p *Parser) AfterDate(threshold time.Time) *Parser {
-	p.afterDate = &threshold
+// Strict stops parsing at the first rejected line instead of skipping it.
+func (p *Parser) Strict() *Parser {
+	p.strict = true
 	return p
 }
 
+// KeepUnstructured keeps lines that are not JSON, such as output from other
+// libraries or panics, in ParsedLogs.Unstructured instead of rejecting them.
+func (p *Parser) KeepUnstructured() *Parser {
+	p.keepUnstructured = true
+	return p
+}
+
+// Parse reads the whole input and groups the entries by UUID.
+// Traces are ordered by the first appearance of their UUID in the input.
+// Filters are applied to each frame before grouping: traces only hold their
+// matching frames, or all of them with KeepWholeTraces, and traces without any
+// matching frame are left out. Rejected lines are listed in Errors; with Strict, parsing stops at the first one.
 func (p *Parser) Parse() ParsedLogs {
 	var parsed ParsedLogs
-	traceMap := make(map[string][]Output)
-	traceError := make(map[string]string)
+	builders := make(map[string]*traceBuilder)
+	var order []string
+	var cp *Checkpoint
+	var raw map[string][]Output // Frames as read, by UUID, to hold open traces
+	var latest time.Time
+	if len(p.sources) == 1 {
+		cp = &Checkpoint{}
+		if p.resume != nil {
+			cp.LastDate, cp.LastSeq = p.resume.LastDate, p.resume.LastSeq
+		}
+		if p.holdQuiet > 0 {
+			raw = make(map[string][]Output)
+		}
+	}
+
+	add := func(entry dated) {
+		if entry.date.After(latest) {
+			latest = entry.date
+		}
+		matched := p.matchesDated(entry)
+		if entry.UUID == "" {
+			if matched {
+				parsed.Entries = append(parsed.Entries, entry.Output)
+			}
+			return
+		}
+		b, ok := builders[entry.UUID]
+		if !ok {
+			b = newTraceBuilder(entry.UUID)
+			builders[entry.UUID] = b
+			order = append(order, entry.UUID)
+		}
+		if raw != nil {
+			raw[entry.UUID] = append(raw[entry.UUID], entry.Output)
+		}
+		b.add(entry.Output, entry.date, matched, p.keepWholeTraces)
+	}
 
-	for _, line := range p.lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	if cp != nil && p.resume != nil {
+		for _, frame := range p.resume.Open {
+			add(newDated(frame))
+		}
+	}
+	for entry, err := range p.decodeDated(cp) {
+		if err != nil {
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				pe = &ParseError{Err: err}
+			}
+			if p.keepUnstructured && pe.Err == ErrUnstructured {
+				parsed.Unstructured = append(parsed.Unstructured, RawLine{Source: pe.Source, Line: pe.Line, Text: pe.Raw})
+			} else {
+				parsed.Errors = append(parsed.Errors, *pe)
+			}
 			continue
 		}
-		var entry Output
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		for _, frame := range expandDated(entry) {
+			add(frame)
+		}
+	}
+
+	for _, uuid := range order {
+		b := builders[uuid]
+		if raw != nil && !b.last.Add(p.holdQuiet).Before(latest) {
+			cp.Open = append(cp.Open, raw[uuid]...)
 			continue
 		}
-		if p.afterDate != nil {
-			t, err := time.Parse(TimeLayout, entry.Date)
-			if err != nil || !t.After(*p.afterDate) {
+		if b.matched {
+			parsed.Traces = append(parsed.Traces, b.build())
+		}
+	}
+	linkJoinedTraces(parsed.Traces)
+	if cp != nil && p.sources[0].path != "" {
+		cp.identify(p.sources[0].path)
+	}
+	parsed.Checkpoint = cp
+	return parsed
+}
+
+// Entries decodes the input line by line, with constant memory when reading from
+// a file or reader. Rejected lines are reported as a *ParseError and iteration
+// continues, unless Strict is set; a read error is reported last.
+// Entries that do not match the filters are skipped. With KeepUnstructured, lines
+// that are not JSON are reported as a *ParseError wrapping ErrUnstructured with the
+// whole line in Raw, and they do not stop a strict parser.
+// Deferred chain entries (see SetDeferredChains) are yielded as written, with their
+// Chain, when any of their frames matches.
+func (p *Parser) Entries() iter.Seq2[Output, error] {
+	return func(yield func(Output, error) bool) {
+		for entry, err := range p.decodeDated(nil) {
+			if err == nil && !slices.ContainsFunc(expandDated(entry), p.matchesDated) {
 				continue
 			}
+			if !yield(entry.Output, err) {
+				return
+			}
+		}
+	}
+}
+
+// decode yields every decoded entry and rejected line, without applying filters.
+// Several sources are merged by date.
+func (p *Parser) decode() iter.Seq2[Output, error] {
+	return func(yield func(Output, error) bool) {
+		for entry, err := range p.decodeDated(nil) {
+			if !yield(entry.Output, err) {
+				return
+			}
+		}
+	}
+}
+
+// decodeDated is decode with the dates of the entries. With a single source,
+// cp is updated as lines are read.
+func (p *Parser) decodeDated(cp *Checkpoint) iter.Seq2[dated, error] {
+	if len(p.sources) == 1 {
+		return p.decodeSource(p.sources[0], p.offsets[p.sources[0].name], cp)
+	}
+	return p.mergeSources()
+}
+
+// decodeSource yields the entries of one source, with their dates shifted by offset.
+// Reading starts where ResumeFrom says, and cp, if set, records how far it went.
+// Files are decoded on several goroutines with Workers.
+func (p *Parser) decodeSource(src *logSource, offset time.Duration, cp *Checkpoint) iter.Seq2[dated, error] {
+	return func(yield func(dated, error) bool) {
+		pos, skip := p.resumePosition(src)
+		if cp != nil {
+			cp.Offset, cp.Line = pos.offset, pos.line
 		}
-		if entry.UUID != "" {
-			if traceError[entry.UUID] == "" && entry.Error != "" {
-				traceError[entry.UUID] = entry.Error
+		emit := func(line decodedLine) bool {
+			switch {
+			case !line.ok:
+				cp.track(line.pos, nil)
+				return true
+			case line.err == nil:
+				cp.track(line.pos, &line.entry.Output)
+				if skip != nil && !skip.after(line.entry.Output) {
+					return true
+				}
+				src.tag(&line.entry, offset)
+				return yield(line.entry, nil)
+			case p.resume != nil && !line.pos.terminated:
+				return false // Possibly still being written, left for the next run
+			default:
+				if !p.stopsAt(line.err) {
+					cp.track(line.pos, nil)
+				}
+				return yield(dated{}, line.err) && !p.stopsAt(line.err)
 			}
-			entry.Error = "" // Clear after saving
-			traceMap[entry.UUID] = append(traceMap[entry.UUID], entry)
+		}
+
+		var err error
+		if p.workers > 1 && src.path != "" && !isCompressedFile(src.path) {
+			err = p.decodeChunks(src, &pos, emit)
 		} else {
-			parsed.Entries = append(parsed.Entries, entry)
+			err = src.scanLines(p.lineLimit(), p.decompress, &pos, func(lineNo int, line string, tooLong bool) bool {
+				entry, ok, err := p.decodeLine(src.label(), lineNo, line, tooLong)
+				return emit(decodedLine{pos: pos, entry: entry, err: err, ok: ok})
+			})
+		}
+		if err != nil {
+			yield(dated{}, &ParseError{Source: src.label(), Err: err})
 		}
 	}
+}
 
-	for uuid, frames := range traceMap {
-		sort.Slice(frames, func(i, j int) bool {
-			t1, _ := time.Parse(TimeLayout, frames[i].Date)
-			t2, _ := time.Parse(TimeLayout, frames[j].Date)
-			return t1.Before(t2)
-		})
-		parsed.Traces = append(parsed.Traces, ParsedErrorTrace{
-			UUID:   uuid,
-			Error:  traceError[uuid],
-			Frames: frames,
-		})
+// resumePosition returns where to start reading a source, see ResumeFrom, and the
+// checkpoint whose entries must be skipped when the file was replaced since.
+func (p *Parser) resumePosition(src *logSource) (scanPosition, *Checkpoint) {
+	c := p.resume
+	if c == nil || len(p.sources) != 1 {
+		return scanPosition{}, nil
 	}
-	return parsed
+	if src.path != "" && !c.sameFile(src.path) {
+		return scanPosition{}, c
+	}
+	return scanPosition{offset: c.Offset, line: c.Line}, nil
+}
+
+// stopsAt reports whether parsing stops after the error, see Strict.
+func (p *Parser) stopsAt(err error) bool {
+	return p.strict && !(p.keepUnstructured && errors.Is(err, ErrUnstructured))
+}
+
+// decodeLine decodes one line, or reports why it is rejected as a *ParseError.
+// It reports false for blank lines, which are skipped.
+func (p *Parser) decodeLine(source string, lineNo int, line string, tooLong bool) (dated, bool, error) {
+	reject := func(err error) (dated, bool, error) {
+		return dated{}, true, &ParseError{Source: source, Line: lineNo, Raw: rawSnippet(line), Err: err}
+	}
+	if tooLong {
+		return reject(fmt.Errorf("%w: more than %d bytes", ErrLineTooLong, p.lineLimit()))
+	}
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return dated{}, false, nil
+	}
+	if !strings.HasPrefix(trimmed, "{") {
+		if p.keepUnstructured {
+			return dated{}, true, &ParseError{Source: source, Line: lineNo, Raw: line, Err: ErrUnstructured}
+		}
+		return reject(ErrUnstructured)
+	}
+
+	var output Output
+	if err := json.Unmarshal([]byte(trimmed), &output); err != nil {
+		return reject(err)
+	}
+	return newDated(output), true, nil
+}
+
+// Traces assembles traces while streaming the input, instead of waiting for the end
+// like Parse. A trace is emitted once no entry with its UUID has been seen for the
+// quiet window, measured with the dates of the entries, and every remaining trace
+// is emitted at the end of the input. Only open traces are kept in memory.
+// Entries without a UUID are skipped, and JoinedBy is not filled since the traces
+// joining a trace may appear after it was emitted. Filters apply as in Parse.
+// Decoding errors are yielded as in Entries.
+func (p *Parser) Traces(quiet time.Duration) iter.Seq2[ParsedErrorTrace, error] {
+	return func(yield func(ParsedErrorTrace, error) bool) {
+		w := newTraceWindow(quiet)
+		for entry, err := range p.decodeDated(nil) {
+			if err != nil {
+				if !yield(ParsedErrorTrace{}, err) {
+					return
+				}
+				continue
+			}
+			if !p.addToWindow(w, entry, yield) {
+				return
+			}
+		}
+		w.flush(nil, yield)
+	}
+}
+
+// addToWindow adds the frames of an entry to the open traces, and emits the traces
+// that went quiet meanwhile. It reports false when yield asked to stop.
+func (p *Parser) addToWindow(w *traceWindow, entry dated, yield func(ParsedErrorTrace, error) bool) bool {
+	for _, f := range expandDated(entry) {
+		if f.UUID == "" {
+			continue
+		}
+		now := w.add(f, p.matchesDated(f), p.keepWholeTraces)
+		if !w.flush(&now, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// traceWindow holds the traces being assembled while streaming.
+type traceWindow struct {
+	quiet      time.Duration
+	open       map[string]*list.Element
+	byLastSeen *list.List // Of *traceBuilder, least recently seen first
+}
+
+func newTraceWindow(quiet time.Duration) *traceWindow {
+	return &traceWindow{quiet: quiet, open: make(map[string]*list.Element), byLastSeen: list.New()}
+}
+
+// add records a frame in its trace and returns the latest date of the trace.
+func (w *traceWindow) add(frame dated, matched, keepAll bool) time.Time {
+	e, ok := w.open[frame.UUID]
+	if !ok {
+		e = w.byLastSeen.PushBack(newTraceBuilder(frame.UUID))
+		w.open[frame.UUID] = e
+	}
+	b := e.Value.(*traceBuilder)
+	b.add(frame.Output, frame.date, matched, keepAll)
+	w.byLastSeen.MoveToBack(e)
+	return b.last
+}
+
+// flush emits the traces that went quiet before now, or all of them when now is nil.
+// It reports false when yield asked to stop.
+func (w *traceWindow) flush(now *time.Time, yield func(ParsedErrorTrace, error) bool) bool {
+	for e := w.byLastSeen.Front(); e != nil; e = w.byLastSeen.Front() {
+		b := e.Value.(*traceBuilder)
+		if now != nil && !b.last.Add(w.quiet).Before(*now) {
+			return true
+		}
+		w.byLastSeen.Remove(e)
+		delete(w.open, b.trace.UUID)
+		if b.matched && !yield(b.build(), nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("nabu: %s: %v", e.Source, e.Err)
+	}
+	if e.Source == "" {
+		return fmt.Sprintf("nabu: line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("nabu: %s:%d: %v", e.Source, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// rawSnippet shortens a rejected line so that errors stay readable.
+func rawSnippet(line string) string {
+	if len(line) <= rawSnippetSize {
+		return line
+	}
+	return strings.ToValidUTF8(line[:rawSnippetSize], "") + "..."
+}
+
+func (p *Parser) lineLimit() int {
+	if p.maxLineSize > 0 {
+		return p.maxLineSize
+	}
+	return DefaultMaxLineSize
+}
+
+// traceBuilder accumulates the frames of one trace.
+type traceBuilder struct {
+	trace   ParsedErrorTrace
+	times   []time.Time // Parsed date of each frame, parsed once
+	last    time.Time   // Latest date seen for the trace
+	matched bool        // Whether any frame matched the filters
+}
+
+func newTraceBuilder(uuid string) *traceBuilder {
+	return &traceBuilder{trace: ParsedErrorTrace{UUID: uuid}}
+}
+
+// add records a frame dated t, moving its error information to the trace.
+// The frame is only kept if it matched the filters, or if keepAll is set.
+func (b *traceBuilder) add(entry Output, t time.Time, matched, keepAll bool) {
+	if b.trace.Error == "" && entry.Error != "" {
+		b.trace.Error = entry.Error
+	}
+	if b.trace.Errors == nil && len(entry.Errors) > 0 {
+		b.trace.Errors = entry.Errors
+	}
+	if b.trace.ParentUUID == "" {
+		b.trace.ParentUUID = entry.ParentUUID
+	}
+	b.trace.Related = appendUnique(b.trace.Related, entry.Related...)
+	entry.Error = "" // Clear after saving
+	entry.Errors = nil
+	entry.Related = nil
+	entry.ParentUUID = ""
+
+	if t.After(b.last) {
+		b.last = t
+	}
+	b.matched = b.matched || matched
+	if !matched && !keepAll {
+		return
+	}
+	b.trace.Frames = append(b.trace.Frames, entry)
+	b.times = append(b.times, t)
+}
+
+// build returns the trace with its frames ordered oldest to newest.
+// Frames logged within the same microsecond are ordered by Seq.
+func (b *traceBuilder) build() ParsedErrorTrace {
+	indexes := make([]int, len(b.trace.Frames))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		ti, tj := b.times[indexes[i]], b.times[indexes[j]]
+		if ti.Equal(tj) {
+			return b.trace.Frames[indexes[i]].Seq < b.trace.Frames[indexes[j]].Seq
+		}
+		return ti.Before(tj)
+	})
+
+	t := b.trace
+	t.Frames = make([]Output, len(indexes))
+	for i, index := range indexes {
+		t.Frames[i] = b.trace.Frames[index]
+	}
+	return t
+}
+
+// dated is a decoded entry along with its parsed Date, so that dates are parsed once.
+type dated struct {
+	Output
+	date time.Time // Zero when Date is empty or invalid
+}
+
+func newDated(o Output) dated {
+	t, _ := time.Parse(TimeLayout, o.Date)
+	return dated{Output: o, date: t}
+}
+
+// expandDated is ExpandChain for a dated entry.
+func expandDated(entry dated) []dated {
+	if len(entry.Chain) == 0 {
+		return []dated{entry}
+	}
+	frames := ExpandChain(entry.Output)
+	result := make([]dated, len(frames))
+	for i, f := range frames {
+		result[i] = newDated(f)
+	}
+	return result
+}
+
+// ExpandChain turns an entry written by a boundary (see SetDeferredChains) into
+// one entry per frame, as Parser groups them: with the UUID of the entry, and its
+// error on the first frame. Other entries are returned unchanged.
+func ExpandChain(entry Output) []Output {
+	if len(entry.Chain) == 0 {
+		return []Output{entry}
+	}
+	frames := make([]Output, len(entry.Chain))
+	for i, f := range entry.Chain {
+		f.UUID = entry.UUID
+		if f.Date == "" {
+			f.Date = entry.Date
+		}
+		if i == 0 {
+			f.Error, f.Errors, f.Related, f.ParentUUID = entry.Error, entry.Errors, entry.Related, entry.ParentUUID
+		}
+		frames[i] = f
+	}
+	return frames
+}
+
+// Trace returns the trace with the given UUID, used to follow Related and JoinedBy links.
+func (p ParsedLogs) Trace(uuid string) (ParsedErrorTrace, bool) {
+	for _, t := range p.Traces {
+		if t.UUID == uuid {
+			return t, true
+		}
+	}
+	return ParsedErrorTrace{}, false
+}
+
+// linkJoinedTraces fills JoinedBy so that joins can be followed in both directions.
+func linkJoinedTraces(traces []ParsedErrorTrace) {
+	index := make(map[string]int, len(traces))
+	for i, t := range traces {
+		index[t.UUID] = i
+	}
+	for _, t := range traces {
+		for _, related := range t.Related {
+			if i, ok := index[related]; ok {
+				traces[i].JoinedBy = appendUnique(traces[i].JoinedBy, t.UUID)
+			}
+		}
+	}
+	for i := range traces {
+		sort.Strings(traces[i].JoinedBy)
+	}
+}
+
+// appendUnique appends the values not already present in s.
+func appendUnique(s []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(s, v) {
+			s = append(s, v)
+		}
+	}
+	return s
 }
@@ -1,6 +1,12 @@
 package nabu
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -122,3 +128,276 @@ func TestParserCases(t *testing.T) {
 		}
 	})
 }
+
+func TestParserJoinedTraces(t *testing.T) {
+	logs := []string{
+		`{"UUID":"a","Date":"2025-06-25 01:01:00.000000","Error":"A failed","Function":"worker","Line":1,"Level":3}`,
+		`{"UUID":"b","Date":"2025-06-25 01:01:00.000100","Error":"B failed","Function":"worker","Line":1,"Level":3}`,
+		`{"UUID":"j","Date":"2025-06-25 01:01:01.000000","Errors":["A failed","B failed"],"Related":["a","b"],"Msg":"workers failed","Function":"run","Line":9,"Level":3}`,
+		`{"UUID":"j","Date":"2025-06-25 01:01:02.000000","Msg":"request failed","Function":"handle","Line":4,"Level":3}`,
+	}
+	parsed := NewParser().FromLines(logs).Parse()
+
+	joined, ok := parsed.Trace("j")
+	if !ok {
+		t.Fatal("expected trace j")
+	}
+	if !reflect.DeepEqual(joined.Related, []string{"a", "b"}) {
+		t.Errorf("expected Related [a b], got %v", joined.Related)
+	}
+	if !reflect.DeepEqual(joined.Errors, []string{"A failed", "B failed"}) {
+		t.Errorf("expected Errors to be kept, got %v", joined.Errors)
+	}
+	if len(joined.Frames) != 2 || joined.Frames[0].Related != nil || joined.Frames[0].Errors != nil {
+		t.Errorf("expected frames without join data, got %+v", joined.Frames)
+	}
+
+	for _, uuid := range []string{"a", "b"} {
+		trace, _ := parsed.Trace(uuid)
+		if !reflect.DeepEqual(trace.JoinedBy, []string{"j"}) {
+			t.Errorf("trace %s: expected JoinedBy [j], got %v", uuid, trace.JoinedBy)
+		}
+	}
+
+	if _, ok := parsed.Trace("missing"); ok {
+		t.Error("expected no trace for unknown UUID")
+	}
+}
+
+func TestParserEntries(t *testing.T) {
+	input := strings.Join([]string{
+		`{"Date":"2025-06-25 01:00:00.000000","Msg":"first","Level":1}`,
+		``,
+		`not json`,
+		`{"Date":"2025-06-25 01:01:00.000000","Msg":"second","Level":1}`,
+	}, "\n")
+
+	var msgs []string
+	var errs []error
+	for entry, err := range NewParser().FromReader(strings.NewReader(input)).Entries() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		msgs = append(msgs, entry.Msg)
+	}
+	if !reflect.DeepEqual(msgs, []string{"first", "second"}) {
+		t.Errorf("expected [first second], got %v", msgs)
+	}
+	var parseErr *ParseError
+	if len(errs) != 1 || !errors.As(errs[0], &parseErr) || parseErr.Line != 3 {
+		t.Errorf("expected a ParseError on line 3, got %v", errs)
+	}
+
+	t.Run("Stops early", func(t *testing.T) {
+		n := 0
+		for range NewParser().FromString(input).Entries() {
+			n++
+			break
+		}
+		if n != 1 {
+			t.Errorf("expected 1 iteration, got %d", n)
+		}
+	})
+}
+
+func TestParserFromFileLazy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if _, err := NewParser().FromFile(path); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	line := `{"UUID":"a","Date":"2025-06-25 01:00:00.000000","Error":"boom","Level":3}` + "\n"
+	if err := os.WriteFile(path, []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parser, err := NewParser().FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(line+line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parsed := parser.Parse()
+	if len(parsed.Traces) != 1 || len(parsed.Traces[0].Frames) != 2 {
+		t.Errorf("expected the file to be read when parsing, got %+v", parsed.Traces)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	parsed = parser.Parse()
+	if len(parsed.Errors) != 1 || parsed.Errors[0].Line != 0 || !errors.Is(&parsed.Errors[0], os.ErrNotExist) {
+		t.Errorf("expected the open error in Errors, got %+v", parsed.Errors)
+	}
+}
+
+func TestParserFromFileUnreadable(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root can read any file")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o200); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewParser().FromFile(path); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+	if _, err := NewParser().AddFile("app", path); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+}
+
+func TestParserTraceOrder(t *testing.T) {
+	logs := []string{
+		`{"UUID":"c","Date":"2025-06-25 01:00:00.000000","Level":3}`,
+		`{"UUID":"a","Date":"2025-06-25 01:00:01.000000","Level":3}`,
+		`{"UUID":"b","Date":"2025-06-25 01:00:02.000000","Level":3}`,
+		`{"UUID":"a","Date":"2025-06-25 01:00:03.000000","Level":3}`,
+	}
+	for range 10 {
+		var uuids []string
+		for _, trace := range NewParser().FromLines(logs).Parse().Traces {
+			uuids = append(uuids, trace.UUID)
+		}
+		if !reflect.DeepEqual(uuids, []string{"c", "a", "b"}) {
+			t.Fatalf("expected traces in order of first appearance, got %v", uuids)
+		}
+	}
+}
+
+func TestParserTraces(t *testing.T) {
+	logs := []string{
+		`{"UUID":"a","Date":"2025-06-25 01:00:00.000000","Error":"A failed","Function":"query","Level":3}`,
+		`{"UUID":"b","Date":"2025-06-25 01:00:01.000000","Error":"B failed","Function":"read","Level":3}`,
+		`{"UUID":"a","Date":"2025-06-25 01:00:02.000000","Function":"handle","Level":3}`,
+		`{"Date":"2025-06-25 01:00:03.000000","Msg":"no uuid","Level":1}`,
+		`broken`,
+		`{"UUID":"c","Date":"2025-06-25 01:00:20.000000","Error":"C failed","Level":3}`,
+		`{"UUID":"b","Date":"2025-06-25 01:00:21.000000","Function":"late","Level":3}`,
+	}
+
+	var uuids []string
+	var frames []int
+	errCount := 0
+	for trace, err := range NewParser().FromLines(logs).Traces(10 * time.Second) {
+		if err != nil {
+			errCount++
+			continue
+		}
+		uuids = append(uuids, trace.UUID)
+		frames = append(frames, len(trace.Frames))
+	}
+
+	// a and b went quiet before c arrived, so the late b entry starts a new trace
+	if !reflect.DeepEqual(uuids, []string{"b", "a", "c", "b"}) {
+		t.Errorf("expected traces [b a c b], got %v", uuids)
+	}
+	if !reflect.DeepEqual(frames, []int{1, 2, 1, 1}) {
+		t.Errorf("expected frame counts [1 2 1 1], got %v", frames)
+	}
+	if errCount != 1 {
+		t.Errorf("expected 1 decoding error, got %d", errCount)
+	}
+
+	t.Run("Matches Parse without quiet window", func(t *testing.T) {
+		var streamed []ParsedErrorTrace
+		for trace, err := range NewParser().FromLines(logs).Traces(time.Hour) {
+			if err == nil {
+				streamed = append(streamed, trace)
+			}
+		}
+		parsed := NewParser().FromLines(logs).Parse()
+		if len(streamed) != len(parsed.Traces) {
+			t.Fatalf("expected %d traces, got %d", len(parsed.Traces), len(streamed))
+		}
+		for _, trace := range streamed {
+			want, _ := parsed.Trace(trace.UUID)
+			if !reflect.DeepEqual(trace, want) {
+				t.Errorf("trace %s: expected %+v, got %+v", trace.UUID, want, trace)
+			}
+		}
+	})
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	long := `{"Date":"2025-06-25 01:00:02.000000","Args":["` + strings.Repeat("x", 100) + `"],"Level":2}`
+	input := strings.Join([]string{
+		`{"Date":"2025-06-25 01:00:00.000000","Msg":"ok","Level":2}`,
+		`panic: runtime error`,
+		`{"Date":"2025-06-25 01:00:01.000000","Msg":`,
+		long,
+		`{"Date":"yesterday","Msg":"bad date","Level":2}`,
+		`{"Date":"2025-06-25 01:00:03.000000","Msg":"last","Level":2}`,
+	}, "\n")
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parser, err := NewParser().FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := parser.MaxLineSize(80).Parse()
+
+	if len(parsed.Entries) != 3 || parsed.Entries[2].Msg != "last" {
+		t.Errorf("expected reading to continue after rejected lines, got %+v", parsed.Entries)
+	}
+	if len(parsed.Entries) > 1 && parsed.Entries[1].Msg != "bad date" {
+		t.Errorf("expected an entry with an unknown date format to be kept, got %+v", parsed.Entries[1])
+	}
+	wantErrs := []error{ErrUnstructured, nil, ErrLineTooLong}
+	if len(parsed.Errors) != len(wantErrs) {
+		t.Fatalf("expected %d errors, got %+v", len(wantErrs), parsed.Errors)
+	}
+	for i, want := range wantErrs {
+		e := parsed.Errors[i]
+		if e.Source != path || e.Line != i+2 {
+			t.Errorf("error %d: expected %s:%d, got %s:%d", i, path, i+2, e.Source, e.Line)
+		}
+		if want != nil && !errors.Is(&e, want) {
+			t.Errorf("error %d: expected %v, got %v", i, want, e.Err)
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(parsed.Errors[1].Err, &syntaxErr) {
+		t.Errorf("expected a JSON syntax error, got %v", parsed.Errors[1].Err)
+	}
+	if parsed.Errors[0].Raw != "panic: runtime error" {
+		t.Errorf("expected the raw line, got %q", parsed.Errors[0].Raw)
+	}
+	if !strings.Contains(parsed.Errors[2].Error(), "app.log:4: line too long") {
+		t.Errorf("unexpected message %q", parsed.Errors[2].Error())
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		parsed := NewParser().FromString(input).Strict().Parse()
+		if len(parsed.Entries) != 1 || len(parsed.Errors) != 1 {
+			t.Errorf("expected parsing to stop at the first error, got %d entries and %d errors", len(parsed.Entries), len(parsed.Errors))
+		}
+	})
+
+	t.Run("KeepUnstructured", func(t *testing.T) {
+		parsed := NewParser().FromReader(strings.NewReader(input)).KeepUnstructured().Strict().Parse()
+		want := []RawLine{{Line: 2, Text: "panic: runtime error"}}
+		if !reflect.DeepEqual(parsed.Unstructured, want) {
+			t.Errorf("expected %+v, got %+v", want, parsed.Unstructured)
+		}
+		if len(parsed.Errors) != 1 || parsed.Errors[0].Line != 3 {
+			t.Errorf("expected strict parsing to stop on line 3, got %+v", parsed.Errors)
+		}
+	})
+
+	t.Run("Lines longer than the read buffer", func(t *testing.T) {
+		huge := `{"Date":"2025-06-25 01:00:00.000000","Msg":"` + strings.Repeat("y", 200000) + `","Level":2}`
+		parsed := NewParser().FromReader(strings.NewReader(huge + "\n" + huge)).Parse()
+		if len(parsed.Entries) != 2 || len(parsed.Errors) != 0 {
+			t.Fatalf("expected 2 entries, got %d entries and %+v", len(parsed.Entries), parsed.Errors)
+		}
+		parsed = NewParser().FromReader(strings.NewReader(huge + "\n" + huge)).MaxLineSize(100000).Parse()
+		if len(parsed.Errors) != 2 || len(parsed.Errors[0].Raw) > rawSnippetSize+3 {
+			t.Errorf("expected 2 errors with short snippets, got %d", len(parsed.Errors))
+		}
+	})
+}
@@ -0,0 +1,677 @@
+package nabu
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryResult holds the entries selected by Parser.Query, with the selected
+// columns rendered as text.
+type QueryResult struct {
+	Columns []string     // Names of the selected columns
+	Rows    [][]string   // One row per entry, one value per column
+	Entries []Output     // The selected entries, in the order of Rows
+	Errors  []ParseError // Lines that were rejected, in input order, as in ParsedLogs.Errors
+}
+
+// defaultColumns are selected when a query has no SELECT clause.
+var defaultColumns = []string{"date", "level", "uuid", "fn", "msg", "error"}
+
+// queryDateLayouts are the layouts accepted for dates in queries, so that a
+// date can be written with the precision needed, e.g. "2025-06-25".
+var queryDateLayouts = []string{TimeLayout, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", time.RFC3339Nano}
+
+// Query selects entries with a small query language, for example:
+//
+//	SELECT date, fn, msg WHERE level>=warn AND fn~"db\." AND args.userID=42 AND date>"2025-06-25" ORDER BY date DESC LIMIT 20
+//
+// Every clause is optional and WHERE may be omitted. Conditions are combined with
+// AND, OR, NOT and parentheses, and compare a field with a value using = != > >= < <=,
+// or ~ and !~ for regular expressions. Fields are date, level, uuid, fn (or function),
+// line, msg (or message), error, code, category, retryable, args.<key> for a value
+// of Args (see ArgValue) and fields.<key> for a static field. Values containing spaces or
+// operators must be quoted. Dates are in the local format of the log and may omit
+// the time or its fractional part.
+//
+// The query runs on every frame, like filters set on the Parser, which also apply.
+// Rejected lines are skipped and listed in QueryResult.Errors, unless Strict is set,
+// in which case the first one is returned as the error. An input that cannot be
+// read is returned as a *ParseError with Line 0.
+func (p *Parser) Query(query string) (QueryResult, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	var entries []Output
+	var rejected []ParseError
+	for entry, err := range p.decode() {
+		if err != nil {
+			var pe *ParseError
+			if p.stopsAt(err) || !errors.As(err, &pe) || pe.Line == 0 {
+				return QueryResult{}, err
+			}
+			if !(p.keepUnstructured && errors.Is(err, ErrUnstructured)) {
+				rejected = append(rejected, *pe)
+			}
+			continue
+		}
+		for _, f := range ExpandChain(entry) {
+			if p.matches(f) && (q.where == nil || q.where.eval(f)) {
+				entries = append(entries, f)
+			}
+		}
+		if len(q.orderBy) == 0 && q.limit >= 0 && len(entries) >= q.limit {
+			break
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b Output) int {
+		for _, o := range q.orderBy {
+			c := compareQueryValues(o.field.value(a), o.field.value(b))
+			if o.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	if q.limit >= 0 && len(entries) > q.limit {
+		entries = entries[:q.limit]
+	}
+
+	result := QueryResult{Entries: entries, Errors: rejected}
+	for _, f := range q.columns {
+		result.Columns = append(result.Columns, f.name)
+	}
+	for _, e := range entries {
+		row := make([]string, len(q.columns))
+		for i, f := range q.columns {
+			row[i] = f.text(e)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// compiledQuery is a parsed query, ready to be evaluated.
+type compiledQuery struct {
+	columns []queryField
+	where   queryNode // nil when there is no condition
+	orderBy []queryOrder
+	limit   int // -1 when there is no limit
+}
+
+type queryOrder struct {
+	field queryField
+	desc  bool
+}
+
+// queryNode is a condition of the WHERE clause.
+type queryNode interface {
+	eval(o Output) bool
+}
+
+type queryAnd []queryNode
+type queryOr []queryNode
+type queryNot struct{ node queryNode }
+
+func (n queryAnd) eval(o Output) bool {
+	for _, c := range n {
+		if !c.eval(o) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n queryOr) eval(o Output) bool {
+	for _, c := range n {
+		if c.eval(o) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n queryNot) eval(o Output) bool {
+	return !n.node.eval(o)
+}
+
+// queryCondition compares a field with a value.
+type queryCondition struct {
+	field queryField
+	op    string
+	value any            // Converted to the type of the field
+	re    *regexp.Regexp // For ~ and !~
+}
+
+func (c queryCondition) eval(o Output) bool {
+	v := c.field.value(o)
+	if v == nil {
+		return false // Missing args match no condition, like NULL in SQL
+	}
+	switch c.op {
+	case "~":
+		return c.re.MatchString(queryText(v))
+	case "!~":
+		return !c.re.MatchString(queryText(v))
+	}
+
+	var r int
+	if _, ok := c.field.kind.(string); ok {
+		r = strings.Compare(queryText(v), c.value.(string))
+	} else {
+		r = compareQueryValues(v, c.value)
+	}
+	switch c.op {
+	case "=":
+		return r == 0
+	case "!=":
+		return r != 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	default: // "<="
+		return r <= 0
+	}
+}
+
+// queryField is a column that can be selected, compared and sorted.
+type queryField struct {
+	name  string
+	kind  any // Zero value of the type returned by value, nil for args
+	value func(o Output) any
+}
+
+// text renders the value of the field for QueryResult.Rows.
+func (f queryField) text(o Output) string {
+	switch f.name {
+	case "date":
+		return o.Date
+	case "args", "fields":
+		v := f.value(o)
+		if v == nil {
+			return ""
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	v := f.value(o)
+	if v == nil {
+		return ""
+	}
+	return queryText(v)
+}
+
+func lookupQueryField(name string) (queryField, error) {
+	lower := strings.ToLower(name)
+	if key, ok := cutKeyPrefix(name, "args."); ok {
+		return queryField{name: "args." + key, value: func(o Output) any {
+			v, _ := ArgValue(o.Args, key)
+			return v
+		}}, nil
+	}
+	if key, ok := cutKeyPrefix(name, "fields."); ok {
+		return queryField{name: "fields." + key, value: func(o Output) any { return o.Fields[key] }}, nil
+	}
+
+	str := func(get func(o Output) string) func(o Output) any {
+		return func(o Output) any { return get(o) }
+	}
+	switch lower {
+	case "date":
+		return queryField{name: lower, kind: time.Time{}, value: func(o Output) any {
+			t, _ := time.Parse(TimeLayout, o.Date)
+			return t
+		}}, nil
+	case "level":
+		return queryField{name: lower, kind: LogLevel(0), value: func(o Output) any { return o.Level }}, nil
+	case "line":
+		return queryField{name: lower, kind: float64(0), value: func(o Output) any { return float64(o.Line) }}, nil
+	case "retryable":
+		return queryField{name: lower, kind: false, value: func(o Output) any { return o.Retryable }}, nil
+	case "uuid":
+		return queryField{name: lower, kind: "", value: str(func(o Output) string { return o.UUID })}, nil
+	case "fn", "function":
+		return queryField{name: lower, kind: "", value: str(func(o Output) string { return o.Function })}, nil
+	case "msg", "message":
+		return queryField{name: lower, kind: "", value: str(func(o Output) string { return o.Msg })}, nil
+	case "error":
+		return queryField{name: lower, kind: "", value: str(func(o Output) string {
+			if o.Error == "" {
+				return strings.Join(o.Errors, "; ")
+			}
+			return o.Error
+		})}, nil
+	case "code":
+		return queryField{name: lower, kind: "", value: str(func(o Output) string { return o.Code })}, nil
+	case "category":
+		return queryField{name: lower, kind: "", value: str(func(o Output) string {
+			if o.Category == CategoryNone {
+				return ""
+			}
+			return o.Category.String()
+		})}, nil
+	case "args":
+		return queryField{name: lower, value: func(o Output) any { return o.Args }}, nil
+	case "fields":
+		return queryField{name: lower, value: func(o Output) any {
+			if len(o.Fields) == 0 {
+				return nil
+			}
+			return o.Fields
+		}}, nil
+	}
+	return queryField{}, fmt.Errorf("nabu: unknown query field %q", name)
+}
+
+// cutKeyPrefix returns the key of a field such as "args.<key>", the prefix being
+// matched regardless of case.
+func cutKeyPrefix(name, prefix string) (string, bool) {
+	if len(name) > len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+		return name[len(prefix):], true
+	}
+	return "", false
+}
+
+// compareQueryValues orders two values of a field. Numbers are compared
+// numerically when both sides are numbers, anything else as text.
+func compareQueryValues(a, b any) int {
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case LogLevel:
+		if y, ok := b.(LogLevel); ok {
+			return cmp.Compare(x, y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return cmp.Compare(strconv.FormatBool(x), strconv.FormatBool(y))
+		}
+	}
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	if x, ok := queryNumber(a); ok {
+		if y, ok := queryNumber(b); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+	return strings.Compare(queryText(a), queryText(b))
+}
+
+func queryNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func queryText(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case map[string]any, []any:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// parseQuery compiles a query as accepted by Parser.Query.
+func parseQuery(query string) (*compiledQuery, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	qp := &queryParser{tokens: tokens}
+	q := &compiledQuery{limit: -1}
+
+	if qp.keyword("SELECT") {
+		for {
+			t, err := qp.expectWord("a column")
+			if err != nil {
+				return nil, err
+			}
+			f, err := lookupQueryField(t.text)
+			if err != nil {
+				return nil, err
+			}
+			q.columns = append(q.columns, f)
+			if !qp.symbol(",") {
+				break
+			}
+		}
+	} else {
+		for _, name := range defaultColumns {
+			f, _ := lookupQueryField(name)
+			q.columns = append(q.columns, f)
+		}
+	}
+
+	qp.keyword("WHERE")
+	if !qp.atEnd() && !qp.peekKeyword("ORDER") && !qp.peekKeyword("LIMIT") {
+		if q.where, err = qp.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if qp.keyword("ORDER") {
+		if !qp.keyword("BY") {
+			return nil, qp.errorf("expected BY after ORDER")
+		}
+		for {
+			t, err := qp.expectWord("a column")
+			if err != nil {
+				return nil, err
+			}
+			f, err := lookupQueryField(t.text)
+			if err != nil {
+				return nil, err
+			}
+			o := queryOrder{field: f}
+			if qp.keyword("DESC") {
+				o.desc = true
+			} else {
+				qp.keyword("ASC")
+			}
+			q.orderBy = append(q.orderBy, o)
+			if !qp.symbol(",") {
+				break
+			}
+		}
+	}
+
+	if qp.keyword("LIMIT") {
+		t, err := qp.expectWord("a number")
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(t.text)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("nabu: invalid query LIMIT %q", t.text)
+		}
+		q.limit = n
+	}
+
+	if !qp.atEnd() {
+		return nil, qp.errorf("unexpected %q", qp.peek().text)
+	}
+	return q, nil
+}
+
+type queryTokenKind int
+
+const (
+	tokenWord   queryTokenKind = iota // Keyword, field or unquoted value
+	tokenString                       // Quoted value
+	tokenSymbol                       // Operator, parenthesis or comma
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int // Byte offset in the query
+}
+
+// queryOperators lists the comparison operators, longest first.
+var queryOperators = []string{"!=", ">=", "<=", "!~", "=", ">", "<", "~"}
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, queryToken{kind: tokenSymbol, text: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(query) && query[j] != c; j++ {
+				// Only the quote and the backslash are escaped, so that regular
+				// expressions such as "db\." can be written as is
+				if query[j] == '\\' && j+1 < len(query) && (query[j+1] == c || query[j+1] == '\\') {
+					j++
+				}
+				sb.WriteByte(query[j])
+			}
+			if j == len(query) {
+				return nil, fmt.Errorf("nabu: unterminated string in query at offset %d", i)
+			}
+			tokens = append(tokens, queryToken{kind: tokenString, text: sb.String(), pos: i})
+			i = j + 1
+		default:
+			if op := queryOperatorAt(query[i:]); op != "" {
+				tokens = append(tokens, queryToken{kind: tokenSymbol, text: op, pos: i})
+				i += len(op)
+				continue
+			}
+			j := i
+			for j < len(query) && !unicode.IsSpace(rune(query[j])) && !strings.ContainsRune("()\",'", rune(query[j])) && queryOperatorAt(query[j:]) == "" {
+				j++
+			}
+			tokens = append(tokens, queryToken{kind: tokenWord, text: query[i:j], pos: i})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func queryOperatorAt(s string) string {
+	for _, op := range queryOperators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// queryParser parses the WHERE clause by recursive descent.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (qp *queryParser) atEnd() bool {
+	return qp.pos >= len(qp.tokens)
+}
+
+func (qp *queryParser) peek() queryToken {
+	return qp.tokens[qp.pos]
+}
+
+func (qp *queryParser) peekKeyword(kw string) bool {
+	return !qp.atEnd() && qp.peek().kind == tokenWord && strings.EqualFold(qp.peek().text, kw)
+}
+
+// keyword consumes the keyword if it is next.
+func (qp *queryParser) keyword(kw string) bool {
+	if qp.peekKeyword(kw) {
+		qp.pos++
+		return true
+	}
+	return false
+}
+
+// symbol consumes the symbol if it is next.
+func (qp *queryParser) symbol(s string) bool {
+	if !qp.atEnd() && qp.peek().kind == tokenSymbol && qp.peek().text == s {
+		qp.pos++
+		return true
+	}
+	return false
+}
+
+func (qp *queryParser) expectWord(what string) (queryToken, error) {
+	if qp.atEnd() || qp.peek().kind != tokenWord {
+		return queryToken{}, qp.errorf("expected %s", what)
+	}
+	qp.pos++
+	return qp.tokens[qp.pos-1], nil
+}
+
+func (qp *queryParser) errorf(format string, args ...any) error {
+	if qp.atEnd() {
+		return fmt.Errorf("nabu: query: %s at end of query", fmt.Sprintf(format, args...))
+	}
+	return fmt.Errorf("nabu: query: %s at offset %d", fmt.Sprintf(format, args...), qp.peek().pos)
+}
+
+func (qp *queryParser) parseOr() (queryNode, error) {
+	var nodes queryOr
+	for {
+		n, err := qp.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !qp.keyword("OR") {
+			break
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (qp *queryParser) parseAnd() (queryNode, error) {
+	var nodes queryAnd
+	for {
+		n, err := qp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !qp.keyword("AND") {
+			break
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (qp *queryParser) parseUnary() (queryNode, error) {
+	if qp.keyword("NOT") {
+		n, err := qp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{n}, nil
+	}
+	if qp.symbol("(") {
+		n, err := qp.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !qp.symbol(")") {
+			return nil, qp.errorf("expected )")
+		}
+		return n, nil
+	}
+	return qp.parseCondition()
+}
+
+func (qp *queryParser) parseCondition() (queryNode, error) {
+	t, err := qp.expectWord("a field")
+	if err != nil {
+		return nil, err
+	}
+	f, err := lookupQueryField(t.text)
+	if err != nil {
+		return nil, err
+	}
+
+	if qp.atEnd() || qp.peek().kind != tokenSymbol || !slices.Contains(queryOperators, qp.peek().text) {
+		return nil, qp.errorf("expected an operator after %q", t.text)
+	}
+	op := qp.peek().text
+	qp.pos++
+
+	if qp.atEnd() || qp.peek().kind == tokenSymbol {
+		return nil, qp.errorf("expected a value after %q", t.text+op)
+	}
+	raw := qp.peek().text
+	qp.pos++
+
+	c := queryCondition{field: f, op: op}
+	if op == "~" || op == "!~" {
+		if c.re, err = regexp.Compile(raw); err != nil {
+			return nil, fmt.Errorf("nabu: query: invalid regular expression for %s: %w", t.text, err)
+		}
+		return c, nil
+	}
+	if c.value, err = convertQueryValue(f, raw); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// convertQueryValue converts a value of the query to the type of the field.
+func convertQueryValue(f queryField, raw string) (any, error) {
+	switch f.kind.(type) {
+	case time.Time:
+		for _, layout := range queryDateLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("nabu: query: invalid date %q", raw)
+	case LogLevel:
+		if n, err := strconv.Atoi(raw); err == nil {
+			return LogLevel(n), nil
+		}
+		l, err := ParseLevel(raw)
+		if err != nil {
+			return nil, fmt.Errorf("nabu: query: %w", err)
+		}
+		return l, nil
+	case float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("nabu: query: %s expects a number, got %q", f.name, raw)
+		}
+		return n, nil
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("nabu: query: %s expects true or false, got %q", f.name, raw)
+		}
+		return b, nil
+	}
+	return raw, nil
+}
@@ -0,0 +1,133 @@
+package nabu
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func queryMessages(t *testing.T, query string) []string {
+	t.Helper()
+	result, err := NewParser().FromLines(filterLogs).Query(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	var msgs []string
+	for _, e := range result.Entries {
+		msgs = append(msgs, e.Msg)
+	}
+	return msgs
+}
+
+func TestParserQuery(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{``, []string{"started", "query failed", "request failed", "fetch failed", "retrying"}},
+		{`level>=warn`, []string{"query failed", "request failed", "fetch failed"}},
+		{`WHERE level = error AND fn~"^db\."`, []string{"query failed"}},
+		{`args.userID=42`, []string{"query failed", "retrying"}},
+		{`fields.service=api`, []string{"query failed"}},
+		{`fields.userID=42`, nil},
+		{`args.service=api`, nil},
+		{`args.userID<10`, []string{"fetch failed"}},
+		{`args.userID!=42`, []string{"fetch failed"}},
+		{`date>"2025-06-25 01:00:02" AND date<"2025-06-26"`, []string{"fetch failed", "retrying"}},
+		{`date<2025-06-25`, nil},
+		{`msg="request failed" OR uuid=b`, []string{"request failed", "fetch failed"}},
+		{`NOT (level<warn OR error~timeout)`, []string{"query failed", "request failed"}},
+		{`error!~.`, []string{"started", "request failed", "retrying"}},
+		{`line>=8 ORDER BY line DESC`, []string{"fetch failed", "query failed", "retrying"}},
+		{`ORDER BY level, date DESC LIMIT 3`, []string{"retrying", "started", "fetch failed"}},
+		{`level>debug LIMIT 2`, []string{"started", "query failed"}},
+		{`msg='it\'s'`, nil},
+	}
+	for _, c := range cases {
+		if got := queryMessages(t, c.query); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.query, c.want, got)
+		}
+	}
+}
+
+func TestParserQueryColumns(t *testing.T) {
+	result, err := NewParser().FromLines(filterLogs).Query(`SELECT level, fn, args.userID, args, fields.service, fields WHERE uuid=a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Columns, []string{"level", "fn", "args.userID", "args", "fields.service", "fields"}) {
+		t.Errorf("unexpected columns %v", result.Columns)
+	}
+	want := [][]string{
+		{"error", "db.Query", "42", `["userID",42]`, "api", `{"service":"api"}`},
+		{"error", "http.handle", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("expected %v, got %v", want, result.Rows)
+	}
+
+	result, _ = NewParser().FromLines(filterLogs).Query(`uuid=b`)
+	want = [][]string{{"2025-06-25 01:00:03.000000", "warn", "b", "http.fetch", "fetch failed", "timeout"}}
+	if !reflect.DeepEqual(result.Columns, defaultColumns) || !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("expected default columns %v, got %v %v", want, result.Columns, result.Rows)
+	}
+}
+
+func TestParserQueryWithFilters(t *testing.T) {
+	result, err := NewParser().FromLines(filterLogs).UUID("a").Query(`level=error`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 2 {
+		t.Errorf("expected the parser filters to apply, got %d entries", len(result.Entries))
+	}
+
+	_, err = NewParser().FromLines(append([]string{"{broken"}, filterLogs...)).Strict().Query(``)
+	if err == nil {
+		t.Error("expected strict parsing to fail")
+	}
+}
+
+func TestParserQueryRejectedLines(t *testing.T) {
+	result, err := NewParser().FromLines(append([]string{"{broken"}, filterLogs...)).Query(`level=error`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Line != 1 || len(result.Entries) == 0 {
+		t.Errorf("expected the rejected line to be listed, got %+v", result.Errors)
+	}
+
+	r := io.MultiReader(strings.NewReader(filterLogs[0]+"\n"), iotest.ErrReader(errors.New("disk gone")))
+	_, err = NewParser().FromReader(r).Query(`level=error`)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 0 || !strings.Contains(err.Error(), "disk gone") {
+		t.Errorf("expected the read error to be returned, got %v", err)
+	}
+}
+
+func TestParserQueryErrors(t *testing.T) {
+	cases := map[string]string{
+		`level>=loud`:          "unknown log level",
+		`colour=red`:           "unknown query field",
+		`level`:                "expected an operator",
+		`level=`:               "expected a value",
+		`(level=warn`:          "expected )",
+		`fn~"("`:               "invalid regular expression",
+		`date>yesterday`:       "invalid date",
+		`line>many`:            "expects a number",
+		`msg="open`:            "unterminated string",
+		`ORDER date`:           "expected BY",
+		`LIMIT -1`:             "invalid query LIMIT",
+		`level=warn level`:     "unexpected",
+		`SELECT WHERE level=1`: "unknown query field",
+	}
+	for query, want := range cases {
+		_, err := NewParser().FromLines(filterLogs).Query(query)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", query, want, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package nabu
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the values hidden by SetRedaction.
+const Redacted = "[REDACTED]"
+
+// Redaction hides sensitive values from written entries, e.g. passwords or card numbers.
+type Redaction struct {
+	Keys     []string         // Names of Args whose values are hidden, compared case-insensitively
+	Patterns []*regexp.Regexp // Hidden in Msg, Error, Errors and the strings of Args
+}
+
+// redactor is a Redaction prepared for writeLog.
+type redactor struct {
+	keys     map[string]bool // Lowercase
+	patterns []*regexp.Regexp
+}
+
+// redaction is the Redaction applied to written entries, protected by configMutex.
+var redaction redactor
+
+// SetRedaction configures the values hidden from every written entry, and from
+// the frames of deferred chains. Args are hidden under keys of maps and structs,
+// and after keys logged as key/value pairs, e.g. WithArgs("password", p).
+// A zero Redaction hides nothing.
+func SetRedaction(r Redaction) {
+	next := newRedactor(r)
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	redaction = next
+}
+
+func newRedactor(r Redaction) redactor {
+	next := redactor{patterns: r.Patterns}
+	for _, k := range r.Keys {
+		if next.keys == nil {
+			next.keys = make(map[string]bool, len(r.Keys))
+		}
+		next.keys[strings.ToLower(k)] = true
+	}
+	return next
+}
+
+// apply returns the entry with its sensitive values hidden. Args are copied,
+// so that the values of the Logger are left untouched.
+func (r redactor) apply(o Output) Output {
+	if r.keys == nil && r.patterns == nil {
+		return o
+	}
+	o.Msg, o.Error = r.text(o.Msg), r.text(o.Error)
+	if o.Errors != nil {
+		errs := make([]string, len(o.Errors))
+		for i, e := range o.Errors {
+			errs[i] = r.text(e)
+		}
+		o.Errors = errs
+	}
+	o.Args = r.value(o.Args)
+	if o.Chain != nil {
+		chain := make([]Output, len(o.Chain))
+		for i, f := range o.Chain {
+			chain[i] = r.apply(f)
+		}
+		o.Chain = chain
+	}
+	return o
+}
+
+func (r redactor) text(s string) string {
+	for _, p := range r.patterns {
+		s = p.ReplaceAllLiteralString(s, Redacted)
+	}
+	return s
+}
+
+// value redacts an argument. Values other than strings, numbers, booleans, []any
+// and map[string]any are converted to their JSON representation first.
+func (r redactor) value(v any) any {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.text(x)
+	case []any:
+		out := make([]any, len(x))
+		for i := 0; i < len(x); i++ {
+			out[i] = r.value(x[i])
+			if k, ok := x[i].(string); ok && r.keys[strings.ToLower(k)] && i+1 < len(x) {
+				out[i+1] = Redacted
+				i++
+			}
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			if r.keys[strings.ToLower(k)] {
+				out[k] = Redacted
+			} else {
+				out[k] = r.value(e)
+			}
+		}
+		return out
+	}
+
+	switch kind := reflect.ValueOf(v).Kind(); {
+	case kind >= reflect.Bool && kind <= reflect.Complex128:
+		return v
+	case kind == reflect.String:
+		return r.text(reflect.ValueOf(v).String())
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v // Reported by toJson
+	}
+	var decoded any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return v
+	}
+	return r.value(decoded)
+}
@@ -0,0 +1,51 @@
+package nabu
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	r := newRedactor(Redaction{Keys: []string{"Password"}, Patterns: []*regexp.Regexp{regexp.MustCompile(`\d{16}`)}})
+	type login struct {
+		User     string
+		Password string
+	}
+
+	args := []any{"password", "hunter2", "card", "4111111111111111", "user", login{"bob", "secret"}, "nested", map[string]any{"PASSWORD": "x", "n": 1}}
+	o := r.apply(Output{Msg: "card 4111111111111111", Error: "bad 4111111111111111", Errors: []string{"4111111111111111"}, Args: args})
+
+	expected := []any{"password", Redacted, "card", Redacted, "user", map[string]any{"User": "bob", "Password": Redacted}, "nested", map[string]any{"PASSWORD": Redacted, "n": 1}}
+	if !reflect.DeepEqual(o.Args, expected) {
+		t.Errorf("Unexpected args:\n%#v", o.Args)
+	}
+	if o.Msg != "card "+Redacted || o.Error != "bad "+Redacted || o.Errors[0] != Redacted {
+		t.Errorf("Unexpected texts: %q %q %q", o.Msg, o.Error, o.Errors)
+	}
+	if args[1] != "hunter2" {
+		t.Error("Expected the Args of the Logger to be left untouched")
+	}
+
+	if o := (redactor{}).apply(Output{Msg: "4111111111111111"}); o.Msg != "4111111111111111" {
+		t.Errorf("Expected a zero Redaction to hide nothing, got %q", o.Msg)
+	}
+}
+
+func TestSetRedaction(t *testing.T) {
+	SetRedaction(Redaction{Keys: []string{"token"}})
+	defer SetRedaction(Redaction{})
+	SetDeferredChains(true)
+	defer SetDeferredChains(false)
+	resetTestState()
+
+	err := FromError(errors.New("denied")).WithArgs("token", "abc").Log()
+	FromError(err).WithMessage("handler").Boundary().Log()
+
+	out := getInternalOutput()
+	if strings.Contains(out, "abc") || !strings.Contains(out, `"token","[REDACTED]"`) {
+		t.Errorf("Expected the frames of the chain to be redacted, got %s", out)
+	}
+}
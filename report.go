@@ -0,0 +1,264 @@
+package nabu
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportFormat selects the format of reports written by WriteReport.
+type ReportFormat int
+
+const (
+	// ReportText is plain text for terminals, colored with ReportOptions.Color
+	ReportText ReportFormat = iota
+	// ReportMarkdown is Markdown, e.g. for pasting into an incident
+	ReportMarkdown
+	// ReportHTML is a self-contained HTML page
+	ReportHTML
+)
+
+// ReportOptions configures WriteReport.
+type ReportOptions struct {
+	Format ReportFormat
+	Color  bool // Whether text reports use ANSI colors
+}
+
+// ParseReportFormat returns the format named "text", "markdown" (or "md") or "html".
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch strings.ToLower(s) {
+	case "text", "":
+		return ReportText, nil
+	case "markdown", "md":
+		return ReportMarkdown, nil
+	case "html":
+		return ReportHTML, nil
+	}
+	return 0, fmt.Errorf("nabu: unknown report format %q", s)
+}
+
+// WriteReport writes a readable report of the trace: its root error first, then
+// each frame with its time since the first frame, Function:Line, Msg and Args.
+func (t ParsedErrorTrace) WriteReport(w io.Writer, opts ReportOptions) error {
+	return writeReport(w, []ParsedErrorTrace{t}, opts)
+}
+
+// WriteReport writes the report of every trace, see ParsedErrorTrace.WriteReport.
+// With ReportHTML, all the traces are on one page.
+func (p ParsedLogs) WriteReport(w io.Writer, opts ReportOptions) error {
+	return writeReport(w, p.Traces, opts)
+}
+
+// reportTrace is a trace prepared for rendering.
+type reportTrace struct {
+	Title   string   // Root error
+	Details []string // UUID and links to other traces
+	Summary string   // Start and duration
+	Frames  []reportFrame
+
+	offsetWidth   int // Width of the widest offset, to align text reports
+	locationWidth int // Width of the widest location
+}
+
+type reportFrame struct {
+	Offset   string
+	Level    LogLevel
+	Location string
+	Msg      string
+	Args     string
+}
+
+func newReportTrace(t ParsedErrorTrace) reportTrace {
+	r := reportTrace{Title: errorKey(t.Error, t.Errors)}
+	if r.Title == "" {
+		r.Title = "(no error)"
+	}
+	r.Details = append(r.Details, "uuid "+t.UUID)
+	if t.ParentUUID != "" {
+		r.Details = append(r.Details, "parent "+t.ParentUUID)
+	}
+	if len(t.Related) > 0 {
+		r.Details = append(r.Details, "joins "+strings.Join(t.Related, ", "))
+	}
+	if len(t.JoinedBy) > 0 {
+		r.Details = append(r.Details, "joined by "+strings.Join(t.JoinedBy, ", "))
+	}
+
+	var first time.Time
+	var last time.Duration
+	for i, f := range t.Frames {
+		rf := reportFrame{Level: f.Level, Msg: f.Msg}
+		d, err := time.Parse(TimeLayout, f.Date)
+		if i == 0 {
+			first = d
+		}
+		if err == nil && !first.IsZero() {
+			last = d.Sub(first)
+			rf.Offset = "+" + last.String()
+		}
+		if f.Function != "" {
+			rf.Location = f.Function + ":" + strconv.Itoa(f.Line)
+		}
+		if f.Args != nil {
+			rf.Args = consoleJson(f.Args)
+		}
+		r.offsetWidth = max(r.offsetWidth, len(rf.Offset))
+		r.locationWidth = max(r.locationWidth, len(rf.Location))
+		r.Frames = append(r.Frames, rf)
+	}
+
+	switch {
+	case len(t.Frames) == 0:
+		r.Summary = "no frames"
+	case len(t.Frames) == 1:
+		r.Summary = t.Frames[0].Date + ", 1 frame"
+	default:
+		r.Summary = fmt.Sprintf("%s, %d frames over %s", t.Frames[0].Date, len(t.Frames), last)
+	}
+	return r
+}
+
+func writeReport(w io.Writer, traces []ParsedErrorTrace, opts ReportOptions) error {
+	reports := make([]reportTrace, len(traces))
+	for i, t := range traces {
+		reports[i] = newReportTrace(t)
+	}
+	switch opts.Format {
+	case ReportMarkdown:
+		return writeMarkdownReport(w, reports)
+	case ReportHTML:
+		return htmlReport.Execute(w, reports)
+	default:
+		return writeTextReport(w, reports, opts.Color)
+	}
+}
+
+// ANSI escape sequences of text reports.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiCyan  = "\x1b[36m"
+)
+
+var levelColors = map[LogLevel]string{
+	LevelDebug: ansiDim,
+	LevelWarn:  "\x1b[33m",
+	LevelError: ansiRed,
+	LevelFatal: ansiBold + ansiRed,
+}
+
+// writeTextReport writes one block per trace:
+//
+//	timeout
+//	  uuid 0a1f  parent 51b0
+//	  2025-06-25 01:00:00.000000, 2 frames over 1.5s
+//	    +0s   ERROR db.Query:10 query failed
+//	                args ["userID",42]
+//	    +1.5s ERROR main.run:20
+func writeTextReport(w io.Writer, reports []reportTrace, color bool) error {
+	paint := func(code, s string) string {
+		if !color || code == "" || s == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	var sb strings.Builder
+	for i, r := range reports {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(paint(ansiBold+ansiRed, r.Title) + "\n")
+		sb.WriteString("  " + paint(ansiDim, strings.Join(r.Details, "  ")) + "\n")
+		sb.WriteString("  " + paint(ansiDim, r.Summary) + "\n")
+		argsIndent := strings.Repeat(" ", 4+r.offsetWidth+1+5+1) // Under the location
+		for _, f := range r.Frames {
+			line := "    " + paint(ansiDim, fmt.Sprintf("%-*s", r.offsetWidth, f.Offset)) + " " +
+				paint(levelColors[f.Level], fmt.Sprintf("%-5s", strings.ToUpper(f.Level.String()))) + " " +
+				paint(ansiCyan, fmt.Sprintf("%-*s", r.locationWidth, f.Location))
+			if f.Msg != "" {
+				line += " " + f.Msg
+			}
+			sb.WriteString(strings.TrimRight(line, " ") + "\n")
+			if f.Args != "" {
+				sb.WriteString(argsIndent + paint(ansiDim, "args") + " " + f.Args + "\n")
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeMarkdownReport(w io.Writer, reports []reportTrace) error {
+	cell := strings.NewReplacer("|", `\|`, "\n", "<br>", "`", "'")
+	var sb strings.Builder
+	for i, r := range reports {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString("### " + cell.Replace(r.Title) + "\n\n")
+		for _, d := range r.Details {
+			sb.WriteString("- " + cell.Replace(d) + "\n")
+		}
+		sb.WriteString("- " + r.Summary + "\n\n")
+		if len(r.Frames) == 0 {
+			continue
+		}
+		sb.WriteString("| Offset | Level | Location | Message | Args |\n|---|---|---|---|---|\n")
+		for _, f := range r.Frames {
+			location, args := f.Location, f.Args
+			if location != "" {
+				location = "`" + cell.Replace(location) + "`"
+			}
+			if args != "" {
+				args = "`" + cell.Replace(args) + "`"
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", f.Offset, f.Level, location, cell.Replace(f.Msg), args)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>nabu report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+section { margin-bottom: 2em; }
+h2 { color: #b00020; font-size: 1.2em; margin-bottom: .3em; }
+.details { color: #666; margin: .2em 0; }
+table { border-collapse: collapse; margin-top: .5em; }
+th, td { text-align: left; padding: .2em .8em; border-bottom: 1px solid #eee; vertical-align: top; }
+code, .offset { font-family: ui-monospace, monospace; }
+.offset { color: #666; text-align: right; }
+.level-warn { color: #a66b00; }
+.level-error, .level-fatal { color: #b00020; }
+</style>
+</head>
+<body>
+{{- range .}}
+<section>
+<h2>{{.Title}}</h2>
+<p class="details">{{range $i, $d := .Details}}{{if $i}} · {{end}}{{$d}}{{end}}</p>
+<p class="details">{{.Summary}}</p>
+{{- if .Frames}}
+<table>
+<tr><th>Offset</th><th>Level</th><th>Location</th><th>Message</th><th>Args</th></tr>
+{{- range .Frames}}
+<tr><td class="offset">{{.Offset}}</td><td class="level-{{.Level}}">{{.Level}}</td><td><code>{{.Location}}</code></td><td>{{.Msg}}</td><td><code>{{.Args}}</code></td></tr>
+{{- end}}
+</table>
+{{- end}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
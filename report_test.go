@@ -0,0 +1,86 @@
+package nabu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var reportLogs = []string{
+	`{"UUID":"a","Date":"2025-06-25 01:00:00.000000","Error":"user 42 not found","ParentUUID":"p","Args":["userID",42],"Msg":"lookup failed","Function":"db.Query","Line":10,"Level":3}`,
+	`{"UUID":"a","Date":"2025-06-25 01:00:01.500000","Msg":"request <failed> | aborted","Function":"http.handle","Line":120,"Level":2}`,
+	`{"UUID":"b","Date":"2025-06-25 01:00:02.000000","Related":["a"],"Function":"main.run","Line":7,"Level":3}`,
+}
+
+func writeTestReport(t *testing.T, parsed ParsedLogs, opts ReportOptions) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := parsed.WriteReport(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriteReportText(t *testing.T) {
+	parsed := NewParser().FromLines(reportLogs).Parse()
+	want := `user 42 not found
+  uuid a  parent p  joined by b
+  2025-06-25 01:00:00.000000, 2 frames over 1.5s
+    +0s   ERROR db.Query:10     lookup failed
+                args ["userID",42]
+    +1.5s WARN  http.handle:120 request <failed> | aborted
+
+(no error)
+  uuid b  joins a
+  2025-06-25 01:00:02.000000, 1 frame
+    +0s ERROR main.run:7
+`
+	if got := writeTestReport(t, parsed, ReportOptions{}); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	colored := writeTestReport(t, parsed, ReportOptions{Color: true})
+	if !strings.Contains(colored, ansiBold+ansiRed+"user 42 not found"+ansiReset) || !strings.Contains(colored, ansiCyan+"db.Query:10    "+ansiReset) {
+		t.Errorf("expected colors, got %q", colored)
+	}
+
+	var buf bytes.Buffer
+	if err := parsed.Traces[1].WriteReport(&buf, ReportOptions{}); err != nil || !strings.HasPrefix(buf.String(), "(no error)\n") {
+		t.Errorf("expected the report of one trace, got %q %v", buf.String(), err)
+	}
+}
+
+func TestWriteReportMarkdown(t *testing.T) {
+	got := writeTestReport(t, NewParser().FromLines(reportLogs[:2]).Parse(), ReportOptions{Format: ReportMarkdown})
+	want := "### user 42 not found\n\n" +
+		"- uuid a\n- parent p\n- 2025-06-25 01:00:00.000000, 2 frames over 1.5s\n\n" +
+		"| Offset | Level | Location | Message | Args |\n|---|---|---|---|---|\n" +
+		"| +0s | error | `db.Query:10` | lookup failed | `[\"userID\",42]` |\n" +
+		"| +1.5s | warn | `http.handle:120` | request <failed> \\| aborted |  |\n"
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestWriteReportHTML(t *testing.T) {
+	got := writeTestReport(t, NewParser().FromLines(reportLogs).Parse(), ReportOptions{Format: ReportHTML})
+	for _, want := range []string{"<!DOCTYPE html>", "<style>", "<h2>user 42 not found</h2>", "request &lt;failed&gt; | aborted", `<td class="level-warn">warn</td>`, "<h2>(no error)</h2>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<script") || strings.Contains(got, "<link") {
+		t.Error("expected a self-contained page")
+	}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	for s, want := range map[string]ReportFormat{"": ReportText, "text": ReportText, "MD": ReportMarkdown, "markdown": ReportMarkdown, "html": ReportHTML} {
+		if got, err := ParseReportFormat(s); err != nil || got != want {
+			t.Errorf("%q: expected %v, got %v %v", s, want, got, err)
+		}
+	}
+	if _, err := ParseReportFormat("pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,131 @@
+package nabu
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedLayout is the suffix of rotated files, e.g. app.log.20250625T012602.408736.
+const rotatedLayout = "20060102T150405.000000"
+
+// Rotation configures the rotation of a FileSink. A zero Rotation never rotates.
+type Rotation struct {
+	MaxSize    int64         // Size in bytes above which the file is rotated, 0 for no limit
+	MaxBackups int           // Number of rotated files kept, all if 0
+	MaxAge     time.Duration // Age after which rotated files are removed, never if 0
+}
+
+// FileSink is a Sink appending entries to a file, rotated according to its Rotation.
+// A rotated file is renamed after the moment it was rotated, e.g.
+// app.log.20250625T012602.408736, so that Parser.Follow continues with the new file
+// and Parser.FromDir reads the archives too.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	enc      Encoder
+	rotation Rotation
+	f        *os.File
+	size     int64
+}
+
+// OpenFileSink opens or creates the file at path, appending the entries rendered by enc.
+func OpenFileSink(path string, enc Encoder, rotation Rotation) (*FileSink, error) {
+	s := &FileSink{path: path, enc: enc, rotation: rotation}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteEntry appends an entry, rotating the file first if it would exceed MaxSize.
+// Errors are dropped, since they cannot be logged; entries written after Close are dropped too.
+func (s *FileSink) WriteEntry(o Output) {
+	line := s.enc(o) + "\n"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return
+	}
+	if s.rotation.MaxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.rotation.MaxSize {
+		if err := s.rotate(); err != nil {
+			return
+		}
+	}
+	n, _ := s.f.WriteString(line)
+	s.size += int64(n)
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, info.Size()
+	return nil
+}
+
+// rotate renames the file, opens a new one and removes the rotated files beyond
+// MaxBackups or older than MaxAge.
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if err := os.Rename(s.path, s.path+"."+now.Format(rotatedLayout)); err != nil {
+		s.f = nil
+		return err
+	}
+	if err := s.open(); err != nil {
+		s.f = nil
+		return err
+	}
+
+	backups := s.backups()
+	for i, b := range backups {
+		if s.rotation.MaxBackups > 0 && i < len(backups)-s.rotation.MaxBackups ||
+			s.rotation.MaxAge > 0 && now.Sub(b.rotated) > s.rotation.MaxAge {
+			os.Remove(b.path)
+		}
+	}
+	return nil
+}
+
+type rotatedFile struct {
+	path    string
+	rotated time.Time
+}
+
+// backups returns the rotated files of the sink, oldest first.
+func (s *FileSink) backups() []rotatedFile {
+	matches, _ := filepath.Glob(s.path + ".*")
+	var backups []rotatedFile
+	for _, m := range matches {
+		t, err := time.Parse(rotatedLayout, strings.TrimPrefix(m, s.path+"."))
+		if err == nil {
+			backups = append(backups, rotatedFile{m, t})
+		}
+	}
+	slices.SortFunc(backups, func(a, b rotatedFile) int { return a.rotated.Compare(b.rotated) })
+	return backups
+}
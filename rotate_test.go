@@ -0,0 +1,70 @@
+package nabu
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSinkRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	s, err := OpenFileSink(path, func(o Output) string { return o.Msg }, Rotation{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, msg := range []string{"one", "two", "three", "four", "five", "six"} {
+		s.WriteEntry(Output{Msg: msg})
+		time.Sleep(time.Millisecond) // Distinct rotation times
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "six\n" {
+		t.Errorf("Expected the current file to hold the last entry, got %q", data)
+	}
+	backups := s.backups()
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 rotated files, got %v", backups)
+	}
+	for i, want := range []string{"three\n", "four\nfive\n"} {
+		data, _ := os.ReadFile(backups[i].path)
+		if string(data) != want {
+			t.Errorf("Backup %d: expected %q, got %q", i, want, data)
+		}
+		if !strings.HasPrefix(filepath.Base(backups[i].path), "app.log.") {
+			t.Errorf("Unexpected backup name %s", backups[i].path)
+		}
+	}
+}
+
+func TestFileSinkMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	old := path + "." + time.Now().Add(-48*time.Hour).UTC().Format(rotatedLayout)
+	if err := os.WriteFile(old, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenFileSink(path, EncodeJSON, Rotation{MaxSize: 1, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.WriteEntry(Output{Msg: "a"})
+	s.WriteEntry(Output{Msg: "b"})
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected the expired backup to be removed, got %v", err)
+	}
+	if len(s.backups()) != 1 {
+		t.Errorf("Expected the fresh backup to be kept, got %v", s.backups())
+	}
+
+	s.Close()
+	s.WriteEntry(Output{Msg: "after close"})
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "after close") {
+		t.Error("Expected entries written after Close to be dropped")
+	}
+}
@@ -0,0 +1,233 @@
+package nabu
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EnvLevel is the environment variable read at startup to configure levels.
+// It accepts the same syntax as SetLevelRules, e.g. "info,github.com/acme/db/*=debug".
+const EnvLevel = "NABU_LEVEL"
+
+var (
+	// levelRules holds the per-package and per-function level overrides
+	levelRules []levelRule
+
+	// levelCache maps a caller PC to the rule resolved for it.
+	// It is replaced whenever the rules change.
+	levelCache = &sync.Map{}
+)
+
+// levelRule overrides the global log level for matching callers.
+type levelRule struct {
+	pattern string
+	level   LogLevel
+}
+
+// ruleMatch is the cached outcome of resolving the rules for one caller.
+type ruleMatch struct {
+	level   LogLevel
+	matched bool
+}
+
+// init applies EnvLevel without an audit entry, since the initial levels are not a change.
+func init() {
+	if spec := os.Getenv(EnvLevel); spec != "" {
+		global, rules, err := parseLevelSpec(spec)
+		if err != nil {
+			FromError(err).WithMessage("invalid " + EnvLevel).WithLevelWarn().Log()
+			return
+		}
+		s := levelState{level: LevelDebug, rules: rules}
+		if global != nil {
+			s.level = *global
+		}
+		configMutex.Lock()
+		defer configMutex.Unlock()
+		applyLevelStateLocked(s)
+	}
+}
+
+// SetLevelRules configures level overrides resolved from the caller's function name.
+// The spec is a comma-separated list of pattern=level entries, for example
+// "github.com/acme/db/*=debug,github.com/acme/http=warn". Patterns match:
+//   - "path/*": the package path and all of its subpackages
+//   - "prefix*": any function whose full name starts with prefix
+//   - anything else: the exact package path or the exact function name
+//
+// When several patterns match, the longest one wins.
+// An entry without a pattern (e.g. "info") sets the global log level.
+// The previous rules are replaced; an empty spec removes all of them.
+// The change is logged as an audit entry, like SetLogLevel.
+func SetLevelRules(spec string) error {
+	global, rules, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	s := currentLevelState()
+	if global != nil {
+		s.level = *global
+	}
+	s.rules = rules
+	changeLevelState(s, 0, "api")
+	return nil
+}
+
+// GetLevelRules returns the configured overrides in the syntax accepted by SetLevelRules.
+func GetLevelRules() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return formatLevelRules(levelRules)
+}
+
+// ClearLevelRules removes all per-package and per-function overrides.
+// The change is logged as an audit entry, like SetLogLevel.
+func ClearLevelRules() {
+	s := currentLevelState()
+	s.rules = nil
+	changeLevelState(s, 0, "api")
+}
+
+// setLevelRulesLocked replaces the rules and invalidates the cache. configMutex must be held.
+func setLevelRulesLocked(rules []levelRule) {
+	levelRules = rules
+	levelCache = &sync.Map{}
+}
+
+// parseLevelSpec parses a spec as accepted by SetLevelRules.
+func parseLevelSpec(spec string) (*LogLevel, []levelRule, error) {
+	var global *LogLevel
+	var rules []levelRule
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, name, found := strings.Cut(entry, "=")
+		if !found {
+			l, err := ParseLevel(entry)
+			if err != nil {
+				return nil, nil, err
+			}
+			global = &l
+			continue
+		}
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, nil, fmt.Errorf("nabu: empty pattern in level rule %q", entry)
+		}
+		if seen[pattern] {
+			return nil, nil, fmt.Errorf("nabu: duplicate level rule for %q", pattern)
+		}
+		l, err := ParseLevel(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		seen[pattern] = true
+		rules = append(rules, levelRule{pattern: pattern, level: l})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].pattern < rules[j].pattern
+	})
+	return global, rules, nil
+}
+
+// formatLevelRules renders rules in the syntax accepted by SetLevelRules.
+func formatLevelRules(rules []levelRule) string {
+	parts := make([]string, len(rules))
+	for i, r := range rules {
+		parts[i] = r.pattern + "=" + r.level.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// shouldLogCaller determines if a log with the given level should be processed,
+// taking into account the overrides matching the function that called Log.
+func shouldLogCaller(l LogLevel) bool {
+	configMutex.RLock()
+	hasRules := len(levelRules) > 0
+	configMutex.RUnlock()
+	if !hasRules {
+		return shouldLog(l)
+	}
+
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(3, pcs) == 0 { // Ignore: runtime.Callers, shouldLogCaller, Log
+		return shouldLog(l)
+	}
+	return shouldLogAt(l, pcs[0])
+}
+
+// shouldLogAt resolves the level for the caller at pc, using the cache when possible.
+func shouldLogAt(l LogLevel, pc uintptr) bool {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	var m ruleMatch
+	if cached, ok := levelCache.Load(pc); ok {
+		m = cached.(ruleMatch)
+	} else {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		m = matchLevelRules(levelRules, frame.Function)
+		levelCache.Store(pc, m)
+	}
+
+	if m.matched {
+		return l >= m.level
+	}
+	return l >= logLevel
+}
+
+// shouldLogFunction resolves the level for the named function without caching,
+// for callers that are not identified by a PC.
+func shouldLogFunction(l LogLevel, function string) bool {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	if m := matchLevelRules(levelRules, function); m.matched {
+		return l >= m.level
+	}
+	return l >= logLevel
+}
+
+// matchLevelRules returns the level of the longest rule matching the function.
+func matchLevelRules(rules []levelRule, function string) ruleMatch {
+	pkg := packageOf(function)
+	var m ruleMatch
+	longest := -1
+	for _, r := range rules {
+		if len(r.pattern) > longest && ruleMatches(r.pattern, pkg, function) {
+			m = ruleMatch{level: r.level, matched: true}
+			longest = len(r.pattern)
+		}
+	}
+	return m
+}
+
+func ruleMatches(pattern, pkg, function string) bool {
+	if base, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return pkg == base || strings.HasPrefix(pkg, base+"/")
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(function, prefix)
+	}
+	return pattern == pkg || pattern == function
+}
+
+// packageOf extracts the package path from a fully qualified function name,
+// e.g. "github.com/acme/db.(*Conn).Query" becomes "github.com/acme/db".
+func packageOf(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
@@ -0,0 +1,143 @@
+package nabu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLevelSpec(t *testing.T) {
+	global, rules, err := parseLevelSpec("info, github.com/acme/http=warn ,github.com/acme/db/*=debug")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if global == nil || *global != LevelInfo {
+		t.Errorf("Expected global level Info, got %v", global)
+	}
+	expected := "github.com/acme/db/*=debug,github.com/acme/http=warn"
+	if formatLevelRules(rules) != expected {
+		t.Errorf("Expected rules %q, got %q", expected, formatLevelRules(rules))
+	}
+
+	invalid := []string{
+		"github.com/acme/db=verbose",
+		"=debug",
+		"a=debug,a=info",
+		"loud",
+	}
+	for _, spec := range invalid {
+		if _, _, err := parseLevelSpec(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
+
+func TestMatchLevelRules(t *testing.T) {
+	_, rules, err := parseLevelSpec("github.com/acme/db/*=debug,github.com/acme/db/pool=error,github.com/acme/http=warn,github.com/acme/http.serve=info,main.run*=fatal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		function string
+		level    LogLevel
+		matched  bool
+	}{
+		{"github.com/acme/db.(*Conn).Query", LevelDebug, true},
+		{"github.com/acme/db/migrate.Run", LevelDebug, true},
+		{"github.com/acme/db/pool.(*Pool).Get", LevelError, true},
+		{"github.com/acme/http.(*Server).Start", LevelWarn, true},
+		{"github.com/acme/http.serve", LevelInfo, true},
+		{"github.com/acme/httputil.Dump", 0, false},
+		{"github.com/acme/dbx.Open", 0, false},
+		{"main.runWorker.func1", LevelFatal, true},
+		{"main.main", 0, false},
+	}
+	for _, c := range cases {
+		m := matchLevelRules(rules, c.function)
+		if m.matched != c.matched || (m.matched && m.level != c.level) {
+			t.Errorf("%s: expected (%v, %v), got (%v, %v)", c.function, c.level, c.matched, m.level, m.matched)
+		}
+	}
+}
+
+func TestPackageOf(t *testing.T) {
+	cases := map[string]string{
+		"github.com/acme/db.(*Conn).Query": "github.com/acme/db",
+		"github.com/acme/db.Open.func1":    "github.com/acme/db",
+		"main.main":                        "main",
+		"gopkg.in/yaml.v3.Unmarshal":       "gopkg.in/yaml",
+	}
+	for function, expected := range cases {
+		if pkg := packageOf(function); pkg != expected {
+			t.Errorf("packageOf(%q): expected %q, got %q", function, expected, pkg)
+		}
+	}
+}
+
+func TestLevelRulesApplyToCaller(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	defer ClearLevelRules()
+	SetLogLevel(LevelDebug)
+
+	if err := SetLevelRules("github.com/rah-0/nabu=error,github.com/rah-0/nabu.logFromVerboseHelper=debug"); err != nil {
+		t.Fatal(err)
+	}
+	if GetLevelRules() != "github.com/rah-0/nabu=error,github.com/rah-0/nabu.logFromVerboseHelper=debug" {
+		t.Errorf("Unexpected rules: %s", GetLevelRules())
+	}
+
+	resetTestState()
+	FromMessage("suppressed by package rule").Log()
+	logFromVerboseHelper("allowed by function rule")
+	FromMessage("allowed as error").WithLevelError().Log()
+
+	output := getInternalOutput()
+	if strings.Contains(output, "suppressed by package rule") {
+		t.Error("Expected Info entry to be suppressed by the package rule")
+	}
+	if !strings.Contains(output, "allowed by function rule") {
+		t.Error("Expected Info entry from helper to be allowed by the function rule")
+	}
+	if !strings.Contains(output, "allowed as error") {
+		t.Error("Expected Error entry to be allowed by the package rule")
+	}
+
+	// Changing the rules must invalidate cached resolutions
+	ClearLevelRules()
+	resetTestState()
+	FromMessage("suppressed by package rule").Log()
+	if getInternalOutput() == "" {
+		t.Error("Expected entry to be logged once rules are cleared")
+	}
+}
+
+func TestSetLevelRulesGlobal(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	defer ClearLevelRules()
+
+	if err := SetLevelRules("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if GetLogLevel() != LevelWarn {
+		t.Errorf("Expected global level Warn, got %v", GetLogLevel())
+	}
+	if GetLevelRules() != "" {
+		t.Errorf("Expected no rules, got %q", GetLevelRules())
+	}
+}
+
+func logFromVerboseHelper(msg string) {
+	FromMessage(msg).Log()
+}
+
+func BenchmarkLevelRulesCached(b *testing.B) {
+	defer ClearLevelRules()
+	if err := SetLevelRules("github.com/rah-0/nabu=fatal"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		FromMessage("filtered").Log()
+	}
+}
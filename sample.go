@@ -0,0 +1,76 @@
+package nabu
+
+import (
+	"sync"
+	"time"
+)
+
+// Sampling limits the entries written for repetitive messages: within each Tick,
+// the first Initial entries with the same level and message are written, then
+// every Thereafter-th. Entries at LevelError and above, and audit entries, are
+// never dropped. A Sampling with neither Initial nor Thereafter writes every entry.
+type Sampling struct {
+	Initial    int           // Entries written before sampling starts
+	Thereafter int           // Write one entry out of Thereafter once Initial is reached, none if 0
+	Tick       time.Duration // Period after which the counts start over, one second if 0
+}
+
+// sampleKey identifies the entries counted together by Sampling.
+type sampleKey struct {
+	level LogLevel
+	msg   string
+}
+
+var (
+	// sampling is the configured Sampling, protected by configMutex
+	sampling Sampling
+
+	// sampleMutex protects sampleCounts and sampleTick
+	sampleMutex sync.Mutex
+
+	// sampleCounts counts the entries of the current tick
+	sampleCounts map[sampleKey]int
+
+	// sampleTick is when the current tick started
+	sampleTick time.Time
+)
+
+// SetSampling configures the sampling of repetitive entries, see Sampling.
+func SetSampling(s Sampling) {
+	configMutex.Lock()
+	sampling = s
+	configMutex.Unlock()
+	resetSampleCounts()
+}
+
+// resetSampleCounts starts counting over, once the Sampling changed.
+func resetSampleCounts() {
+	sampleMutex.Lock()
+	defer sampleMutex.Unlock()
+	sampleCounts = nil
+}
+
+// sampled reports whether an entry is written under the configured Sampling.
+func sampled(level LogLevel, msg string) bool {
+	configMutex.RLock()
+	s := sampling
+	configMutex.RUnlock()
+	if s.Initial == 0 && s.Thereafter == 0 || level >= LevelError {
+		return true
+	}
+	if s.Tick <= 0 {
+		s.Tick = time.Second
+	}
+
+	sampleMutex.Lock()
+	defer sampleMutex.Unlock()
+	now := time.Now()
+	if sampleCounts == nil || now.Sub(sampleTick) >= s.Tick {
+		sampleCounts = make(map[sampleKey]int)
+		sampleTick = now
+	}
+	key := sampleKey{level, msg}
+	sampleCounts[key]++
+	n := sampleCounts[key]
+	return n <= s.Initial || s.Thereafter > 0 && (n-s.Initial)%s.Thereafter == 0
+}
@@ -0,0 +1,61 @@
+package nabu
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	SetSampling(Sampling{Initial: 2, Thereafter: 3, Tick: time.Hour})
+	defer SetSampling(Sampling{})
+	resetTestState()
+
+	for range 8 {
+		FromMessage("repeated").Log()
+		FromError(errSample).Log()
+	}
+	FromMessage("other").Log()
+
+	out := getInternalOutput()
+	// Entries 1, 2, 5 and 8 of "repeated" are kept
+	if n := strings.Count(out, `"Msg":"repeated"`); n != 4 {
+		t.Errorf("Expected 4 sampled entries, got %d", n)
+	}
+	if n := strings.Count(out, `"Error":"sampled"`); n != 8 {
+		t.Errorf("Expected errors never to be sampled, got %d", n)
+	}
+	if !strings.Contains(out, `"Msg":"other"`) {
+		t.Error("Expected other messages to be counted separately")
+	}
+}
+
+func TestSamplingTick(t *testing.T) {
+	SetSampling(Sampling{Initial: 1, Tick: 20 * time.Millisecond})
+	defer SetSampling(Sampling{})
+
+	if !sampled(LevelInfo, "tick") || sampled(LevelInfo, "tick") {
+		t.Fatal("Expected only the first entry of the tick to be kept")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !sampled(LevelInfo, "tick") {
+		t.Error("Expected the counts to start over with the next tick")
+	}
+}
+
+func TestSamplingTickOnly(t *testing.T) {
+	SetSampling(Sampling{Tick: 5 * time.Second})
+	defer SetSampling(Sampling{})
+
+	for range 3 {
+		if !sampled(LevelInfo, "tick only") || !sampled(LevelWarn, "tick only") {
+			t.Fatal("Expected a Sampling without Initial nor Thereafter to keep every entry")
+		}
+	}
+}
+
+var errSample = sampleError{}
+
+type sampleError struct{}
+
+func (sampleError) Error() string { return "sampled" }
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package nabu
+
+// HandleSignals is a no-op on platforms without SIGUSR1 and SIGUSR2.
+func HandleSignals() (stop func()) {
+	return func() {}
+}
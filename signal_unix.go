@@ -0,0 +1,39 @@
+//go:build unix
+
+package nabu
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleSignals lets operators change the log level of a live process.
+// SIGUSR1 lowers the level by one step towards LevelDebug on every delivery,
+// and SIGUSR2 restores the level that was in effect before the first SIGUSR1.
+// The returned function stops listening for the signals.
+func HandleSignals() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for {
+			select {
+			case s := <-ch:
+				if s == syscall.SIGUSR1 {
+					lowerLogLevel("signal")
+				} else {
+					restoreLogLevel("signal")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package nabu
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignals(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelWarn)
+
+	stop := HandleSignals()
+	defer stop()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, LevelInfo)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, LevelWarn)
+}
+
+func waitForLevel(t *testing.T, l LogLevel) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for GetLogLevel() != l && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if GetLogLevel() != l {
+		t.Fatalf("Expected level %v, got %v", l, GetLogLevel())
+	}
+}
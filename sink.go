@@ -0,0 +1,55 @@
+package nabu
+
+import "slices"
+
+// Sink receives every entry written by Log, in addition to the configured output.
+// WriteEntry may be called concurrently from several goroutines.
+type Sink interface {
+	WriteEntry(o Output)
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(o Output)
+
+// WriteEntry calls f(o).
+func (f SinkFunc) WriteEntry(o Output) {
+	f(o)
+}
+
+// sinkEntry wraps a registered Sink so that it can be removed by identity,
+// even if the same Sink is registered more than once.
+type sinkEntry struct {
+	sink Sink
+}
+
+// sinks holds the registered sinks. The slice is replaced, never modified in place,
+// so that writeLog can iterate over a snapshot without holding configMutex.
+var sinks []*sinkEntry
+
+// AddSink registers a Sink that receives every entry written from now on.
+// The returned function removes it; calling it more than once has no effect.
+func AddSink(s Sink) (remove func()) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	e := addSinkLocked(s)
+
+	return func() {
+		configMutex.Lock()
+		defer configMutex.Unlock()
+		removeSinksLocked(e)
+	}
+}
+
+// addSinkLocked registers a Sink. configMutex must be held.
+func addSinkLocked(s Sink) *sinkEntry {
+	e := &sinkEntry{sink: s}
+	sinks = append(slices.Clip(sinks), e)
+	return e
+}
+
+// removeSinksLocked removes registered sinks. configMutex must be held.
+func removeSinksLocked(entries ...*sinkEntry) {
+	sinks = slices.DeleteFunc(slices.Clone(sinks), func(x *sinkEntry) bool {
+		return slices.Contains(entries, x)
+	})
+}
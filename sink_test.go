@@ -0,0 +1,67 @@
+package nabu
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAddSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []Output
+	remove := AddSink(SinkFunc(func(o Output) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, o)
+	}))
+
+	FromMessage("to sink").WithArgs("k", "v").Log()
+	FromMessage("filtered").WithLevelDebug().Log()
+
+	remove()
+	remove() // Removing twice must be harmless
+	FromMessage("after removal").Log()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(received))
+	}
+	if received[0].Msg != "to sink" || received[0].UUID == "" || received[0].Date == "" {
+		t.Errorf("Unexpected entry: %+v", received[0])
+	}
+}
+
+func TestAddSinkSameTwice(t *testing.T) {
+	count := 0
+	s := SinkFunc(func(o Output) { count++ })
+	removeFirst := AddSink(s)
+	removeSecond := AddSink(s)
+
+	FromMessage("twice").Log()
+	removeFirst()
+	FromMessage("once").Log()
+	removeSecond()
+	FromMessage("never").Log()
+
+	if count != 3 {
+		t.Errorf("Expected 3 deliveries, got %d", count)
+	}
+}
+
+func TestSinkMayLog(t *testing.T) {
+	resetTestState()
+	var logged atomic.Bool
+	remove := AddSink(SinkFunc(func(o Output) {
+		if logged.CompareAndSwap(false, true) {
+			FromMessage("logged from sink").Log()
+		}
+	}))
+	defer remove()
+
+	FromMessage("trigger").Log()
+	if !strings.Contains(getInternalOutput(), "logged from sink") {
+		t.Error("Expected entries logged by a sink to be written")
+	}
+}
@@ -0,0 +1,331 @@
+package nabu
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// logSource is one input of a Parser.
+type logSource struct {
+	name   string    // Set on Output.Source, empty for FromReader, FromFile, FromString and FromLines
+	lines  []string  // Read when neither reader nor path is set
+	reader io.Reader // Read lazily instead of lines when set
+	path   string    // Opened lazily instead of lines when set
+}
+
+// AddSource adds a named input, e.g. the logs of one service. Entries read from it
+// have their Source set to name. The entries of all sources are merged by date,
+// each source being expected in chronological order, as written by nabu.
+// The reader is consumed lazily, like with FromReader.
+func (p *Parser) AddSource(name string, r io.Reader) *Parser {
+	p.sources = append(p.sources, &logSource{name: name, reader: r})
+	return p
+}
+
+// AddFile adds the file at path as a named input, see AddSource.
+// The file is opened each time the Parser runs, like with FromFile.
+func (p *Parser) AddFile(name, path string) (*Parser, error) {
+	if err := checkReadable(path); err != nil {
+		return nil, err
+	}
+	p.sources = append(p.sources, &logSource{name: name, path: path})
+	return p, nil
+}
+
+// ClockOffset corrects the clock of a source: offset is added to the dates of its
+// entries before they are merged, filtered and grouped into traces. Offsets can be
+// measured with EstimateOffsets.
+func (p *Parser) ClockOffset(source string, offset time.Duration) *Parser {
+	if p.offsets == nil {
+		p.offsets = make(map[string]time.Duration)
+	}
+	p.offsets[source] = offset
+	return p
+}
+
+// EstimateOffsets estimates the clock offset of every source relative to the
+// reference source, from the traces they share: a service handling a request
+// logs its part of the trace while the caller waits, so the middle of its frames
+// should match the middle of the caller's frames. The estimate of a source is the
+// median of that difference over the shared UUIDs. Sources sharing no UUID with the
+// reference are left out. Files are read again when the Parser runs, while the
+// data read from the readers of AddSource is kept in memory to be parsed again.
+func (p *Parser) EstimateOffsets(reference string) (map[string]time.Duration, error) {
+	type span struct{ first, last time.Time }
+	spans := make(map[string]map[string]*span) // By source, then UUID
+
+	for _, src := range p.sources {
+		spans[src.name] = make(map[string]*span)
+		if src.reader != nil {
+			var read bytes.Buffer
+			r := src.reader
+			src.reader = io.TeeReader(r, &read)
+			defer func() { src.reader = io.MultiReader(&read, r) }()
+		}
+		for entry, err := range p.decodeSource(src, 0, nil) {
+			var pe *ParseError
+			if errors.As(err, &pe) && pe.Line == 0 {
+				return nil, err
+			}
+			if err != nil {
+				continue
+			}
+			for _, f := range expandDated(entry) {
+				t := f.date
+				if f.UUID == "" || t.IsZero() {
+					continue
+				}
+				s, ok := spans[src.name][f.UUID]
+				if !ok {
+					spans[src.name][f.UUID] = &span{t, t}
+					continue
+				}
+				s.first, s.last = minTime(s.first, t), maxTime(s.last, t)
+			}
+		}
+	}
+
+	ref, ok := spans[reference]
+	if !ok {
+		return nil, fmt.Errorf("nabu: unknown source %q", reference)
+	}
+	middle := func(s *span) time.Time {
+		return s.first.Add(s.last.Sub(s.first) / 2)
+	}
+	offsets := make(map[string]time.Duration)
+	for name, bySource := range spans {
+		if name == reference {
+			continue
+		}
+		var diffs []time.Duration
+		for uuid, s := range bySource {
+			if r, ok := ref[uuid]; ok {
+				diffs = append(diffs, middle(r).Sub(middle(s)))
+			}
+		}
+		if len(diffs) > 0 {
+			slices.Sort(diffs)
+			offsets[name] = diffs[len(diffs)/2]
+		}
+	}
+	return offsets, nil
+}
+
+// mergeSources yields the entries of every source, ordered by date.
+// Rejected lines are yielded as soon as they are read.
+func (p *Parser) mergeSources() iter.Seq2[dated, error] {
+	return func(yield func(dated, error) bool) {
+		type head struct {
+			next  func() (dated, error, bool)
+			entry dated
+			ok    bool // Whether entry is set, false once the source is exhausted
+		}
+
+		// advance reads the next entry of a source, and reports false to stop
+		advance := func(h *head) bool {
+			for {
+				entry, err, ok := h.next()
+				if !ok {
+					h.ok = false
+					return true
+				}
+				if err != nil {
+					if !yield(dated{}, err) || p.stopsAt(err) {
+						return false
+					}
+					continue
+				}
+				h.entry, h.ok = entry, true
+				return true
+			}
+		}
+
+		heads := make([]*head, len(p.sources))
+		for i, src := range p.sources {
+			next, stop := iter.Pull2(p.decodeSource(src, p.offsets[src.name], nil))
+			defer stop()
+			heads[i] = &head{next: next}
+			if !advance(heads[i]) {
+				return
+			}
+		}
+
+		for {
+			var oldest *head
+			for _, h := range heads {
+				if h.ok && (oldest == nil || h.entry.date.Before(oldest.entry.date)) {
+					oldest = h
+				}
+			}
+			if oldest == nil {
+				return
+			}
+			if !yield(oldest.entry, nil) || !advance(oldest) {
+				return
+			}
+		}
+	}
+}
+
+// label names the source in errors.
+func (src *logSource) label() string {
+	if src.name != "" {
+		return src.name
+	}
+	return src.path
+}
+
+// tag sets the source of an entry and of its chain frames, and shifts their dates by offset.
+func (src *logSource) tag(entry *dated, offset time.Duration) {
+	shift := func(o *Output) {
+		o.Source = src.name
+		if offset == 0 {
+			return
+		}
+		if t, err := time.Parse(TimeLayout, o.Date); err == nil {
+			o.Date = t.Add(offset).Format(TimeLayout)
+		}
+	}
+	entry.Source = src.name
+	if offset != 0 && !entry.date.IsZero() {
+		entry.date = entry.date.Add(offset)
+		entry.Date = entry.date.Format(TimeLayout)
+	}
+	for i := range entry.Chain {
+		shift(&entry.Chain[i])
+	}
+}
+
+// scanPosition is where a source is read from, and then how far it has been read.
+type scanPosition struct {
+	offset     int64 // Bytes read, up to the end of the current line
+	line       int   // Number of the current line
+	terminated bool  // Whether the current line ends with a newline
+}
+
+// scanLines calls fn with each line of the input and its 1-based number, until fn returns false.
+// Lines longer than the limit are passed truncated, with tooLong set, and reading goes on
+// with the next line. Reading starts at pos, which is kept up to date with each line.
+// Files are decompressed when compressed, and so are readers when decompress is set.
+func (src *logSource) scanLines(limit int, decompress bool, pos *scanPosition, fn func(lineNo int, line string, tooLong bool) bool) error {
+	if src.reader == nil && src.path == "" {
+		start, offset := pos.offset, int64(0)
+		for _, line := range src.lines {
+			offset += int64(len(line)) + 1
+			if offset <= start {
+				continue
+			}
+			pos.offset, pos.line, pos.terminated = offset, pos.line+1, true
+			tooLong := len(line) > limit
+			if tooLong {
+				line = line[:limit]
+			}
+			if !fn(pos.line, line, tooLong) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	r := src.reader
+	var d Decompressor
+	if src.path != "" {
+		f, err := os.Open(src.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if d, err = fileCompression(f); err != nil {
+			return err
+		}
+		if d == nil {
+			if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		r = f
+	} else if decompress {
+		d, r = peekCompression(r)
+	}
+	if d != nil {
+		rc, err := d(r)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		r = rc
+	}
+
+	if (src.path == "" || d != nil) && pos.offset > 0 { // Offsets are in decompressed bytes
+		if _, err := io.CopyN(io.Discard, r, pos.offset); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return scanReader(r, limit, pos, fn)
+}
+
+// scanReader is scanLines for a reader positioned at pos.
+func scanReader(r io.Reader, limit int, pos *scanPosition, fn func(lineNo int, line string, tooLong bool) bool) error {
+	br := bufio.NewReader(r)
+	var buf []byte
+	for {
+		buf = buf[:0]
+		tooLong := false
+		var n int // Bytes read for the line, newline included
+		var err error
+		for {
+			var chunk []byte
+			chunk, err = br.ReadSlice('\n')
+			n += len(chunk)
+			if room := limit - len(buf); len(chunk) > room {
+				buf = append(buf, chunk[:room]...)
+				tooLong = tooLong || len(bytes.TrimRight(chunk[room:], "\r\n")) > 0
+			} else {
+				buf = append(buf, chunk...)
+			}
+			if err != bufio.ErrBufferFull {
+				break
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n == 0 && err == io.EOF {
+			return nil
+		}
+		pos.offset, pos.line, pos.terminated = pos.offset+int64(n), pos.line+1, err == nil
+		line := strings.TrimRight(string(buf), "\r\n")
+		if !fn(pos.line, line, tooLong) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
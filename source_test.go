@@ -0,0 +1,138 @@
+package nabu
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Service api calls service db, whose clock is 2 seconds behind.
+var (
+	apiLogs = strings.Join([]string{
+		`{"UUID":"r1","Date":"2025-06-25 01:00:00.000000","Msg":"calling db","Function":"api.call","Level":1}`,
+		`{"Date":"2025-06-25 01:00:00.500000","Msg":"tick","Level":1}`,
+		`{"UUID":"r1","Date":"2025-06-25 01:00:01.000000","Msg":"db failed","Function":"api.call","Level":3}`,
+		`{"UUID":"r2","Date":"2025-06-25 01:00:10.000000","Msg":"calling db","Function":"api.call","Level":1}`,
+		`{"UUID":"r2","Date":"2025-06-25 01:00:10.400000","Msg":"db done","Function":"api.call","Level":1}`,
+	}, "\n")
+	dbLogs = strings.Join([]string{
+		`{"UUID":"r1","Date":"2025-06-25 00:59:58.400000","Error":"deadlock","Function":"db.exec","Level":3}`,
+		`{"UUID":"r1","Date":"2025-06-25 00:59:58.600000","Msg":"rolled back","Function":"db.exec","Level":3}`,
+		`not json`,
+		`{"UUID":"r2","Date":"2025-06-25 01:00:08.200000","Msg":"query","Function":"db.exec","Level":1}`,
+	}, "\n")
+)
+
+func TestParserMultipleSources(t *testing.T) {
+	parsed := NewParser().AddSource("api", strings.NewReader(apiLogs)).AddSource("db", strings.NewReader(dbLogs)).Parse()
+
+	r1, _ := parsed.Trace("r1")
+	var timeline []string
+	for _, f := range r1.Frames {
+		timeline = append(timeline, f.Source+":"+f.Function)
+	}
+	// Without correction the db frames appear before the call that caused them
+	if want := []string{"db:db.exec", "db:db.exec", "api:api.call", "api:api.call"}; !reflect.DeepEqual(timeline, want) {
+		t.Errorf("expected %v, got %v", want, timeline)
+	}
+	if r1.Error != "deadlock" {
+		t.Errorf("expected the error from db, got %q", r1.Error)
+	}
+	if len(parsed.Entries) != 1 || parsed.Entries[0].Source != "api" {
+		t.Errorf("expected entries tagged with their source, got %+v", parsed.Entries)
+	}
+	if len(parsed.Errors) != 1 || parsed.Errors[0].Source != "db" || parsed.Errors[0].Line != 3 {
+		t.Errorf("expected the error to name its source, got %+v", parsed.Errors)
+	}
+
+	t.Run("Merged by date", func(t *testing.T) {
+		var dates []string
+		for entry, err := range NewParser().AddSource("api", strings.NewReader(apiLogs)).AddSource("db", strings.NewReader(dbLogs)).Entries() {
+			if err == nil {
+				dates = append(dates, entry.Date)
+			}
+		}
+		if !slices.IsSorted(dates) || len(dates) != 8 {
+			t.Errorf("expected 8 entries in date order, got %v", dates)
+		}
+	})
+}
+
+func TestParserClockOffsets(t *testing.T) {
+	dir := t.TempDir()
+	apiPath, dbPath := filepath.Join(dir, "api.log"), filepath.Join(dir, "db.log")
+	for path, content := range map[string]string{apiPath: apiLogs, dbPath: dbLogs} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := NewParser().AddFile("api", apiPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AddFile("db", dbPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AddFile("missing", filepath.Join(dir, "missing.log")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	offsets, err := p.EstimateOffsets("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// r1: api middle 00.500, db middle 58.500 -> 2s; r2: api 10.200, db 08.200 -> 2s
+	if want := map[string]time.Duration{"db": 2 * time.Second}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("expected %v, got %v", want, offsets)
+	}
+	if _, err := p.EstimateOffsets("web"); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+
+	for name, offset := range offsets {
+		p.ClockOffset(name, offset)
+	}
+	r1, _ := p.Parse().Trace("r1")
+	var timeline []string
+	for _, f := range r1.Frames {
+		timeline = append(timeline, f.Date[17:]+" "+f.Source+":"+f.Msg)
+	}
+	want := []string{"00.000000 api:calling db", "00.400000 db:", "00.600000 db:rolled back", "01.000000 api:db failed"}
+	if !reflect.DeepEqual(timeline, want) {
+		t.Errorf("expected %v, got %v", want, timeline)
+	}
+}
+
+func TestParserEstimateOffsetsKeepsReaders(t *testing.T) {
+	p := NewParser().AddSource("api", strings.NewReader(apiLogs)).AddSource("db", strings.NewReader(dbLogs))
+	offsets, err := p.EstimateOffsets("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, offset := range offsets {
+		p.ClockOffset(name, offset)
+	}
+
+	parsed := p.Parse()
+	if len(parsed.Traces) != 2 || len(parsed.Entries) != 1 || len(parsed.Errors) != 1 {
+		t.Fatalf("expected the readers to be parsed after estimating, got %d traces, %d entries and %v",
+			len(parsed.Traces), len(parsed.Entries), parsed.Errors)
+	}
+	r1, _ := parsed.Trace("r1")
+	if len(r1.Frames) != 4 || r1.Frames[0].Source != "api" {
+		t.Errorf("expected the corrected trace, got %+v", r1.Frames)
+	}
+}
+
+func TestParserMultipleSourcesStrict(t *testing.T) {
+	parsed := NewParser().AddSource("api", strings.NewReader(apiLogs)).AddSource("db", strings.NewReader(dbLogs)).Strict().Parse()
+	if len(parsed.Errors) != 1 || !errors.Is(&parsed.Errors[0], ErrUnstructured) {
+		t.Errorf("expected parsing to stop at the first error, got %+v", parsed.Errors)
+	}
+}
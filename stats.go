@@ -0,0 +1,261 @@
+package nabu
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GroupBy selects what Aggregate counts entries by.
+type GroupBy int
+
+const (
+	// GroupByLevel counts every entry and frame by level
+	GroupByLevel GroupBy = iota
+	// GroupByFunction counts every entry and frame by function
+	GroupByFunction
+	// GroupByError counts traces, and entries with an error, by error message
+	GroupByError
+)
+
+// Count is the number of entries for one key, and one time bucket when aggregating over time.
+type Count struct {
+	Start time.Time `json:",omitzero"` // Start of the bucket, zero when not bucketed
+	Key   string
+	Count int
+}
+
+// Counts is a list of counts, exported with WriteCSV and WriteJSON.
+type Counts []Count
+
+// DepthCount is the number of traces having a given number of frames.
+type DepthCount struct {
+	Depth  int
+	Traces int
+}
+
+// DurationStats summarizes the time between the first and the last frame of traces.
+type DurationStats struct {
+	P50 time.Duration
+	P95 time.Duration
+	Max time.Duration
+}
+
+// Stats summarizes parsed logs.
+type Stats struct {
+	Entries       int           // Entries and frames, traces included
+	Traces        int           // Number of traces
+	ByLevel       Counts        // Entries and frames by level, most frequent first
+	ByFunction    Counts        // Entries and frames by function, most frequent first
+	TopErrors     Counts        // Most frequent error messages
+	ChainDepths   []DepthCount  // Distribution of the number of frames per trace
+	TraceDuration DurationStats // Time between the first and the last frame of traces
+}
+
+// Aggregate counts the entries and trace frames by level, by function or by error
+// message (see GroupBy), in buckets of the given duration starting at the Unix epoch.
+// With a zero bucket, the counts cover the whole input and Start is left zero.
+// Counts are ordered by bucket, then by decreasing count and then by key.
+func (p ParsedLogs) Aggregate(groupBy GroupBy, bucket time.Duration) Counts {
+	type bucketKey struct {
+		start time.Time
+		key   string
+	}
+	counts := make(map[bucketKey]int)
+	add := func(date, key string) {
+		var start time.Time
+		if bucket > 0 {
+			t, err := time.Parse(TimeLayout, date)
+			if err != nil {
+				return
+			}
+			start = bucketStart(t, bucket)
+		}
+		counts[bucketKey{start, key}]++
+	}
+
+	if groupBy == GroupByError {
+		for _, e := range p.Entries {
+			if key := errorKey(e.Error, e.Errors); key != "" {
+				add(e.Date, key)
+			}
+		}
+		for _, t := range p.Traces {
+			if key := errorKey(t.Error, t.Errors); key != "" && len(t.Frames) > 0 {
+				add(t.Frames[0].Date, key)
+			}
+		}
+	} else {
+		p.eachFrame(func(o Output) {
+			if groupBy == GroupByLevel {
+				add(o.Date, o.Level.String())
+			} else {
+				add(o.Date, o.Function)
+			}
+		})
+	}
+
+	result := make(Counts, 0, len(counts))
+	for k, n := range counts {
+		result = append(result, Count{Start: k.start, Key: k.key, Count: n})
+	}
+	slices.SortFunc(result, func(a, b Count) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return result
+}
+
+// unixEpoch is where the buckets of Aggregate start.
+var unixEpoch = time.Unix(0, 0).UTC()
+
+// bucketStart returns the start of the bucket holding t.
+func bucketStart(t time.Time, bucket time.Duration) time.Time {
+	since := t.Sub(unixEpoch)
+	start := since - since%bucket
+	if start > since { // Before the epoch, % rounds towards zero
+		start -= bucket
+	}
+	return unixEpoch.Add(start)
+}
+
+// Stats summarizes the parsed logs, listing the topN most frequent errors, or all
+// of them when topN <= 0.
+func (p ParsedLogs) Stats(topN int) Stats {
+	s := Stats{
+		Traces:     len(p.Traces),
+		ByLevel:    p.Aggregate(GroupByLevel, 0),
+		ByFunction: p.Aggregate(GroupByFunction, 0),
+		TopErrors:  p.Aggregate(GroupByError, 0),
+	}
+	p.eachFrame(func(Output) { s.Entries++ })
+	if topN > 0 && len(s.TopErrors) > topN {
+		s.TopErrors = s.TopErrors[:topN]
+	}
+
+	depths := make(map[int]int)
+	var durations []time.Duration
+	for _, t := range p.Traces {
+		depths[len(t.Frames)]++
+		if len(t.Frames) == 0 {
+			continue
+		}
+		first, err1 := time.Parse(TimeLayout, t.Frames[0].Date)
+		last, err2 := time.Parse(TimeLayout, t.Frames[len(t.Frames)-1].Date)
+		if err1 == nil && err2 == nil {
+			durations = append(durations, last.Sub(first))
+		}
+	}
+	for depth, n := range depths {
+		s.ChainDepths = append(s.ChainDepths, DepthCount{Depth: depth, Traces: n})
+	}
+	slices.SortFunc(s.ChainDepths, func(a, b DepthCount) int {
+		return cmp.Compare(a.Depth, b.Depth)
+	})
+
+	if len(durations) > 0 {
+		slices.Sort(durations)
+		s.TraceDuration = DurationStats{
+			P50: percentile(durations, 50),
+			P95: percentile(durations, 95),
+			Max: durations[len(durations)-1],
+		}
+	}
+	return s
+}
+
+// WriteCSV writes the counts with a start,key,count header.
+// Start is formatted with TimeLayout, or left empty when not bucketed.
+func (c Counts) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"start", "key", "count"})
+	for _, n := range c {
+		cw.Write([]string{formatBucket(n.Start), n.Key, strconv.Itoa(n.Count)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the counts as a JSON array.
+func (c Counts) WriteJSON(w io.Writer) error {
+	return writeIndentedJson(w, c)
+}
+
+// WriteCSV writes every statistic as a metric,key,value row, e.g. "level,error,12"
+// or "trace_duration,p95,1.5s".
+func (s Stats) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"metric", "key", "value"})
+	cw.Write([]string{"entries", "", strconv.Itoa(s.Entries)})
+	cw.Write([]string{"traces", "", strconv.Itoa(s.Traces)})
+	for _, section := range []struct {
+		metric string
+		counts Counts
+	}{{"level", s.ByLevel}, {"function", s.ByFunction}, {"error", s.TopErrors}} {
+		for _, n := range section.counts {
+			cw.Write([]string{section.metric, n.Key, strconv.Itoa(n.Count)})
+		}
+	}
+	for _, d := range s.ChainDepths {
+		cw.Write([]string{"chain_depth", strconv.Itoa(d.Depth), strconv.Itoa(d.Traces)})
+	}
+	cw.Write([]string{"trace_duration", "p50", s.TraceDuration.P50.String()})
+	cw.Write([]string{"trace_duration", "p95", s.TraceDuration.P95.String()})
+	cw.Write([]string{"trace_duration", "max", s.TraceDuration.Max.String()})
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the statistics as a JSON object. Durations are in nanoseconds.
+func (s Stats) WriteJSON(w io.Writer) error {
+	return writeIndentedJson(w, s)
+}
+
+// eachFrame calls fn with every entry and every frame of every trace.
+func (p ParsedLogs) eachFrame(fn func(o Output)) {
+	for _, e := range p.Entries {
+		fn(e)
+	}
+	for _, t := range p.Traces {
+		for _, f := range t.Frames {
+			fn(f)
+		}
+	}
+}
+
+// errorKey is the message used to group an error, joined errors being grouped together.
+func errorKey(err string, errs []string) string {
+	if err != "" {
+		return err
+	}
+	return strings.Join(errs, "; ")
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, pct int) time.Duration {
+	rank := (pct*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
+
+func formatBucket(start time.Time) string {
+	if start.IsZero() {
+		return ""
+	}
+	return start.Format(TimeLayout)
+}
+
+func writeIndentedJson(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,135 @@
+package nabu
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var statsLogs = []string{
+	`{"Date":"2025-06-25 01:00:10.000000","Msg":"started","Function":"main.run","Level":1}`,
+	`{"UUID":"a","Date":"2025-06-25 01:00:20.000000","Error":"timeout","Function":"db.Query","Level":3}`,
+	`{"UUID":"a","Date":"2025-06-25 01:00:21.000000","Function":"main.run","Level":3}`,
+	`{"UUID":"b","Date":"2025-06-25 01:01:05.000000","Error":"timeout","Function":"db.Query","Level":3}`,
+	`{"UUID":"c","Date":"2025-06-25 01:01:30.000000","Errors":["refused","timeout"],"Function":"main.run","Level":3}`,
+	`{"UUID":"c","Date":"2025-06-25 01:01:31.000000","Function":"http.handle","Level":3}`,
+	`{"UUID":"c","Date":"2025-06-25 01:01:34.000000","Function":"main.run","Level":3}`,
+}
+
+func TestAggregate(t *testing.T) {
+	parsed := NewParser().FromLines(statsLogs).Parse()
+
+	byLevel := parsed.Aggregate(GroupByLevel, 0)
+	if want := (Counts{{Key: "error", Count: 6}, {Key: "info", Count: 1}}); !reflect.DeepEqual(byLevel, want) {
+		t.Errorf("expected %v, got %v", want, byLevel)
+	}
+
+	minute := func(s string) time.Time {
+		d, _ := time.Parse(TimeLayout, "2025-06-25 01:"+s+":00.000000")
+		return d
+	}
+	byError := parsed.Aggregate(GroupByError, time.Minute)
+	want := Counts{
+		{Start: minute("00"), Key: "timeout", Count: 1},
+		{Start: minute("01"), Key: "refused; timeout", Count: 1},
+		{Start: minute("01"), Key: "timeout", Count: 1},
+	}
+	if !reflect.DeepEqual(byError, want) {
+		t.Errorf("expected %v, got %v", want, byError)
+	}
+
+	byFunction := parsed.Aggregate(GroupByFunction, 0)
+	if byFunction[0] != (Count{Key: "main.run", Count: 4}) || len(byFunction) != 3 {
+		t.Errorf("unexpected counts by function %v", byFunction)
+	}
+}
+
+func TestAggregateBucketsFromEpoch(t *testing.T) {
+	parsed := NewParser().FromLines(statsLogs).Parse()
+
+	// 7 minutes do not divide a day, so buckets aligned on the zero time would differ
+	byLevel := parsed.Aggregate(GroupByLevel, 7*time.Minute)
+	want := Counts{
+		{Start: time.Date(2025, 6, 25, 0, 54, 0, 0, time.UTC), Key: "error", Count: 2},
+		{Start: time.Date(2025, 6, 25, 0, 54, 0, 0, time.UTC), Key: "info", Count: 1},
+		{Start: time.Date(2025, 6, 25, 1, 1, 0, 0, time.UTC), Key: "error", Count: 4},
+	}
+	if !reflect.DeepEqual(byLevel, want) {
+		t.Errorf("expected buckets of 7 minutes from the Unix epoch %v, got %v", want, byLevel)
+	}
+
+	before := bucketStart(time.Date(1969, 12, 31, 23, 59, 0, 0, time.UTC), time.Hour)
+	if !before.Equal(time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected dates before the epoch to round down, got %v", before)
+	}
+}
+
+func TestStatsNoLimit(t *testing.T) {
+	parsed := NewParser().FromLines(statsLogs).Parse()
+	for _, topN := range []int{0, -1} {
+		if s := parsed.Stats(topN); len(s.TopErrors) != 2 {
+			t.Errorf("Stats(%d): expected every error, got %v", topN, s.TopErrors)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := NewParser().FromLines(statsLogs).Parse().Stats(1)
+
+	if s.Entries != 7 || s.Traces != 3 {
+		t.Errorf("expected 7 entries and 3 traces, got %d and %d", s.Entries, s.Traces)
+	}
+	if want := (Counts{{Key: "timeout", Count: 2}}); !reflect.DeepEqual(s.TopErrors, want) {
+		t.Errorf("expected %v, got %v", want, s.TopErrors)
+	}
+	if want := []DepthCount{{1, 1}, {2, 1}, {3, 1}}; !reflect.DeepEqual(s.ChainDepths, want) {
+		t.Errorf("expected %v, got %v", want, s.ChainDepths)
+	}
+	if want := (DurationStats{P50: time.Second, P95: 4 * time.Second, Max: 4 * time.Second}); s.TraceDuration != want {
+		t.Errorf("expected %+v, got %+v", want, s.TraceDuration)
+	}
+}
+
+func TestStatsExport(t *testing.T) {
+	parsed := NewParser().FromLines(statsLogs).Parse()
+
+	var buf bytes.Buffer
+	if err := parsed.Aggregate(GroupByError, time.Minute).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "start,key,count\n2025-06-25 01:00:00.000000,timeout,1\n") {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := parsed.Aggregate(GroupByLevel, 0).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var counts Counts
+	if err := json.Unmarshal(buf.Bytes(), &counts); err != nil || len(counts) != 2 || strings.Contains(buf.String(), "Start") {
+		t.Errorf("unexpected JSON (%v):\n%s", err, buf.String())
+	}
+
+	s := parsed.Stats(5)
+	buf.Reset()
+	if err := s.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{"metric,key,value\n", "entries,,7\n", "error,timeout,2\n", "chain_depth,3,1\n", "trace_duration,p95,4s\n"} {
+		if !strings.Contains(buf.String(), row) {
+			t.Errorf("expected row %q in CSV:\n%s", row, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Stats
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || !reflect.DeepEqual(decoded, s) {
+		t.Errorf("expected JSON to round-trip (%v):\n%s", err, buf.String())
+	}
+}
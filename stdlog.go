@@ -0,0 +1,82 @@
+package nabu
+
+import (
+	"log"
+	"runtime"
+	"strings"
+)
+
+// StdLogPrefix maps a message prefix written through the standard library log
+// package (e.g. "[ERROR]") to the level of the resulting entry.
+type StdLogPrefix struct {
+	Prefix string
+	Level  LogLevel
+}
+
+// stdLogWriter turns each message of the standard library log package into an entry.
+type stdLogWriter struct {
+	level    LogLevel
+	prefixes []StdLogPrefix
+}
+
+// RedirectStdLog sends everything written through the standard library log package
+// to nabu, so third-party libraries produce the same JSON entries as the application.
+// Messages are logged at the given level, unless they start with one of the prefixes,
+// which is then removed from the message and its level used instead. The first
+// matching prefix wins. Entries report the function that called the log package.
+// The returned function restores the previous output, flags and prefix.
+func RedirectStdLog(level LogLevel, prefixes ...StdLogPrefix) (restore func()) {
+	writer, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+
+	log.SetOutput(&stdLogWriter{level: level, prefixes: prefixes})
+	log.SetFlags(0) // Date and file are already part of every entry
+	log.SetPrefix("")
+
+	return func() {
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// Write implements io.Writer. The log package calls it once per message.
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	level := w.level
+	for _, prefix := range w.prefixes {
+		if rest, ok := strings.CutPrefix(msg, prefix.Prefix); ok {
+			msg = strings.TrimSpace(rest)
+			level = prefix.Level
+			break
+		}
+	}
+
+	function, line := stdLogCaller()
+	if !shouldLogFunction(level, function) || !sampled(level, msg) {
+		return len(p), nil
+	}
+
+	x := FromMessage(msg)
+	x.Level = level
+	x.date, x.seq = getDate(), nextSeq()
+	x.function, x.line = function, line
+	writeLog(x.output())
+
+	return len(p), nil
+}
+
+// stdLogCaller returns the first frame outside of the log package and this writer.
+func stdLogCaller() (string, int) {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs) // Ignore: runtime.Callers, stdLogCaller, Write
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if packageOf(frame.Function) != "log" {
+			return frame.Function, frame.Line
+		}
+		if !more {
+			return "", 0
+		}
+	}
+}
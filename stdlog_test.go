@@ -0,0 +1,85 @@
+package nabu
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	resetTestState()
+	restore := RedirectStdLog(LevelInfo, StdLogPrefix{Prefix: "[ERROR]", Level: LevelError}, StdLogPrefix{Prefix: "WARN:", Level: LevelWarn})
+	defer restore()
+
+	_, _, expectedLine, _ := runtime.Caller(0)
+	log.Print("plain message")
+	log.Printf("[ERROR] connection %d lost", 7)
+	log.Println("WARN: slow query")
+
+	lines := strings.Split(strings.TrimSpace(getInternalOutput()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %v", len(lines), lines)
+	}
+
+	expected := []struct {
+		msg   string
+		level LogLevel
+	}{
+		{"plain message", LevelInfo},
+		{"connection 7 lost", LevelError},
+		{"slow query", LevelWarn},
+	}
+	for i, e := range expected {
+		entry := fromJson(lines[i])
+		if entry.Msg != e.msg || entry.Level != e.level {
+			t.Errorf("Entry %d: expected (%q, %v), got (%q, %v)", i, e.msg, e.level, entry.Msg, entry.Level)
+		}
+		if entry.Function != "github.com/rah-0/nabu.TestRedirectStdLog" || entry.Line != expectedLine+i+1 {
+			t.Errorf("Entry %d: expected caller %s:%d, got %s:%d", i, "TestRedirectStdLog", expectedLine+i+1, entry.Function, entry.Line)
+		}
+		if entry.UUID == "" || entry.Date == "" {
+			t.Errorf("Entry %d: expected UUID and Date, got %+v", i, entry)
+		}
+	}
+}
+
+func TestRedirectStdLogLevel(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LevelWarn)
+	resetTestState()
+
+	restore := RedirectStdLog(LevelInfo)
+	defer restore()
+
+	log.Print("filtered out")
+	logger := log.New(log.Writer(), "", 0)
+	logger.Print("custom logger sharing the writer")
+
+	if getInternalOutput() != "" {
+		t.Errorf("Expected Info messages to be filtered, got: %s", getInternalOutput())
+	}
+}
+
+func TestRedirectStdLogRestore(t *testing.T) {
+	writer, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	defer func() {
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(log.Lshortfile)
+	log.SetPrefix("app: ")
+
+	restore := RedirectStdLog(LevelInfo)
+	restore()
+
+	log.Print("back to normal")
+	if !strings.HasPrefix(buf.String(), "app: stdlog_test.go:") {
+		t.Errorf("Expected the previous output, flags and prefix to be restored, got %q", buf.String())
+	}
+}
@@ -0,0 +1,51 @@
+package nabu
+
+// TraceNode is a trace with the traces started in its context, see ParsedLogs.TraceTree.
+type TraceNode struct {
+	Trace    ParsedErrorTrace
+	Children []*TraceNode // Ordered like ParsedLogs.Traces
+}
+
+// TraceTree arranges the traces by ParentUUID (see Logger.WithParentUuid): each
+// trace becomes a child of the trace in whose context it started. The roots are
+// the traces without a parent, or whose parent is not in the logs, in the order
+// of ParsedLogs.Traces. If parents form a cycle, the first trace of the cycle
+// becomes a root.
+func (p ParsedLogs) TraceTree() []*TraceNode {
+	nodes := make(map[string]*TraceNode, len(p.Traces))
+	parentOf := make(map[string]string, len(p.Traces))
+	for _, t := range p.Traces {
+		nodes[t.UUID] = &TraceNode{Trace: t}
+		if t.ParentUUID != "" {
+			parentOf[t.UUID] = t.ParentUUID
+		}
+	}
+
+	var roots []*TraceNode
+	for _, t := range p.Traces {
+		node := nodes[t.UUID]
+		parent, ok := nodes[parentOf[t.UUID]]
+		if !ok || inParentCycle(parentOf, t.UUID) {
+			delete(parentOf, t.UUID)
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return roots
+}
+
+// inParentCycle reports whether following the parents of uuid leads back to it.
+func inParentCycle(parentOf map[string]string, uuid string) bool {
+	seen := map[string]bool{uuid: true}
+	for p, ok := parentOf[uuid]; ok; p, ok = parentOf[p] {
+		if p == uuid {
+			return true
+		}
+		if seen[p] {
+			return false // A cycle above uuid, which will be cut at its first trace
+		}
+		seen[p] = true
+	}
+	return false
+}
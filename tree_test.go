@@ -0,0 +1,102 @@
+package nabu
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSeqAndParentUuid(t *testing.T) {
+	resetTestState()
+
+	ctx := ContextWithUuid(context.Background(), "request-1")
+	inner := FromError(errors.New("EOF")).WithContext(ctx).WithMessage("job failed")
+	inner.Log()
+	outer := FromError(inner).WithMessage("retry failed")
+	outer.Log()
+	FromMessage("unrelated").WithContext(context.Background()).Log()
+
+	parsed := NewParser().FromString(getInternalOutput()).Parse()
+	if len(parsed.Traces) != 2 {
+		t.Fatalf("expected 2 traces, got %+v", parsed.Traces)
+	}
+	trace := parsed.Traces[0]
+	if trace.ParentUUID != "request-1" || outer.ParentUUID != "request-1" {
+		t.Errorf("expected the parent UUID to be inherited, got %q", trace.ParentUUID)
+	}
+	if trace.Frames[0].ParentUUID != "" || trace.Frames[0].Seq == 0 || trace.Frames[1].Seq <= trace.Frames[0].Seq {
+		t.Errorf("expected increasing Seq and no ParentUUID on frames, got %+v", trace.Frames)
+	}
+	if parsed.Traces[1].ParentUUID != "" {
+		t.Errorf("expected no parent without a context UUID, got %q", parsed.Traces[1].ParentUUID)
+	}
+
+	if x := New().WithUuid("a").WithContext(ContextWithUuid(context.Background(), "a")); x.ParentUUID != "" {
+		t.Errorf("expected a chain not to be its own parent, got %q", x.ParentUUID)
+	}
+}
+
+func TestSeqDeferredChain(t *testing.T) {
+	resetTestState()
+	SetDeferredChains(true)
+	defer SetDeferredChains(false)
+
+	err := FromError(errors.New("EOF")).WithParentUuid("parent").WithMessage("read").Log()
+	FromError(err).WithMessage("handle").Boundary().Log()
+
+	parsed := NewParser().FromString(getInternalOutput()).Parse()
+	if len(parsed.Traces) != 1 {
+		t.Fatalf("expected 1 trace, got %+v", parsed.Traces)
+	}
+	trace := parsed.Traces[0]
+	if trace.ParentUUID != "parent" || len(trace.Frames) != 2 || trace.Frames[0].Seq >= trace.Frames[1].Seq {
+		t.Errorf("expected ordered frames under the parent, got %+v", trace)
+	}
+}
+
+func TestParserOrdersFramesBySeq(t *testing.T) {
+	logs := []string{
+		`{"UUID":"a","Date":"2025-06-25 01:00:00.000001","Seq":9,"Msg":"third","Level":3}`,
+		`{"UUID":"a","Date":"2025-06-25 01:00:00.000001","Seq":7,"Msg":"second","Level":3}`,
+		`{"UUID":"a","Date":"2025-06-25 01:00:00.000000","Seq":8,"Msg":"first","Level":3}`,
+	}
+	var msgs []string
+	for _, f := range NewParser().FromLines(logs).Parse().Traces[0].Frames {
+		msgs = append(msgs, f.Msg)
+	}
+	if !reflect.DeepEqual(msgs, []string{"first", "second", "third"}) {
+		t.Errorf("expected frames ordered by date then Seq, got %v", msgs)
+	}
+}
+
+func TestTraceTree(t *testing.T) {
+	logs := []string{
+		`{"UUID":"req","Date":"2025-06-25 01:00:00.000000","Error":"request failed","Level":3}`,
+		`{"UUID":"job1","ParentUUID":"req","Date":"2025-06-25 01:00:01.000000","Error":"job failed","Level":3}`,
+		`{"UUID":"orphan","ParentUUID":"gone","Date":"2025-06-25 01:00:02.000000","Error":"lost","Level":3}`,
+		`{"UUID":"sub","ParentUUID":"job1","Date":"2025-06-25 01:00:03.000000","Error":"sub failed","Level":3}`,
+		`{"UUID":"job2","ParentUUID":"req","Date":"2025-06-25 01:00:04.000000","Error":"job failed","Level":3}`,
+		`{"UUID":"x","ParentUUID":"y","Date":"2025-06-25 01:00:05.000000","Error":"cycle","Level":3}`,
+		`{"UUID":"y","ParentUUID":"x","Date":"2025-06-25 01:00:06.000000","Error":"cycle","Level":3}`,
+	}
+	tree := NewParser().FromLines(logs).Parse().TraceTree()
+
+	var render func(nodes []*TraceNode) []string
+	render = func(nodes []*TraceNode) []string {
+		var out []string
+		for _, n := range nodes {
+			s := n.Trace.UUID
+			if children := render(n.Children); children != nil {
+				s += "(" + strings.Join(children, " ") + ")"
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	want := []string{"req(job1(sub) job2)", "orphan", "x(y)"}
+	if got := render(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
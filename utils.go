@@ -4,16 +4,30 @@ import (
 	"encoding/json"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// sequence numbers the entries of the process, see nextSeq.
+var sequence atomic.Uint64
+
+// nextSeq returns the next sequence number, to order entries created within the
+// same microsecond.
+func nextSeq() uint64 {
+	return sequence.Add(1)
+}
+
 // getDate returns the current UTC time formatted as a string.
-// Format: YYYY-MM-DD HH:MM:SS.microseconds
+// Format: YYYY-MM-DD HH:MM:SS.microseconds, unless changed with SetTimeFormat
 func getDate() string {
+	configMutex.RLock()
+	layout := timeFormat
+	configMutex.RUnlock()
+
 	ct := time.Now().UTC()
-	return ct.Format(TimeLayout)
+	return ct.Format(layout)
 }
 
 // fromJson parses a JSON string into an Output struct.
@@ -57,3 +71,48 @@ func (x *Logger) getFirstTrace() (string, int) {
 	frame, _ := frames.Next()
 	return frame.Function, frame.Line
 }
+
+// chainUuids returns the UUIDs of the nabu chains wrapped by err, without duplicates.
+// It follows both single and multiple (errors.Join) unwrapping, and stops
+// descending at each *Logger since it already carries the UUID of its chain.
+func chainUuids(err error) []string {
+	var uuids []string
+	seen := make(map[string]bool)
+	var walk func(error)
+	walk = func(e error) {
+		switch x := e.(type) {
+		case nil:
+		case *Logger:
+			if x.UUID != "" && !seen[x.UUID] {
+				seen[x.UUID] = true
+				uuids = append(uuids, x.UUID)
+			}
+		case interface{ Unwrap() []error }:
+			for _, inner := range x.Unwrap() {
+				walk(inner)
+			}
+		case interface{ Unwrap() error }:
+			walk(x.Unwrap())
+		}
+	}
+	walk(err)
+	return uuids
+}
+
+// joinedErrors returns the constituents of the first multi-error found by
+// unwrapping err, stopping at nabu Loggers. Returns nil if err joins nothing.
+func joinedErrors(err error) []error {
+	for err != nil {
+		switch x := err.(type) {
+		case *Logger:
+			return nil
+		case interface{ Unwrap() []error }:
+			return x.Unwrap()
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
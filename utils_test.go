@@ -1,6 +1,9 @@
 package nabu
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -20,3 +23,40 @@ func TestToJsonError(t *testing.T) {
 		t.Errorf("Expected error message, got: %s", result)
 	}
 }
+
+func TestChainUuids(t *testing.T) {
+	a := &Logger{UUID: "a"}
+	b := &Logger{UUID: "b"}
+	nested := fmt.Errorf("wrapped: %w", errors.Join(a, fmt.Errorf("again: %w", b), a))
+
+	cases := []struct {
+		err      error
+		expected []string
+	}{
+		{nil, nil},
+		{errors.New("plain"), nil},
+		{a, []string{"a"}},
+		{&Logger{CausedBy: b}, nil},
+		{fmt.Errorf("x: %w", a), []string{"a"}},
+		{nested, []string{"a", "b"}},
+	}
+	for i, c := range cases {
+		if uuids := chainUuids(c.err); !reflect.DeepEqual(uuids, c.expected) {
+			t.Errorf("case %d: expected %v, got %v", i, c.expected, uuids)
+		}
+	}
+}
+
+func TestJoinedErrors(t *testing.T) {
+	e1, e2 := errors.New("one"), errors.New("two")
+
+	if joined := joinedErrors(fmt.Errorf("ctx: %w", errors.Join(e1, e2))); len(joined) != 2 {
+		t.Errorf("Expected 2 joined errors, got %v", joined)
+	}
+	if joined := joinedErrors(&Logger{CausedBy: errors.Join(e1, e2)}); joined != nil {
+		t.Errorf("Expected unwrapping to stop at Logger, got %v", joined)
+	}
+	if joined := joinedErrors(e1); joined != nil {
+		t.Errorf("Expected nil for a single error, got %v", joined)
+	}
+}